@@ -0,0 +1,193 @@
+package gsf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CliAppAdminAddr = "app-admin-addr"
+
+	AdminPprofPath     = "/debug/pprof/"
+	AdminStatsPath     = "/debug/stats"
+	AdminConfigPath    = "/debug/config"
+	AdminComponentPath = "/debug/components"
+)
+
+type _AdminComponent struct {
+	Name    string   `json:"name"`
+	State   string   `json:"state"`
+	Depends []string `json:"depends,omitempty"`
+}
+
+// _AdminAddr 管理服务只允许监听本机地址，未指定 host 时使用 127.0.0.1
+func _AdminAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", errors.New("Admin Addr Invalid: " + err.Error())
+	}
+	switch host {
+	case "":
+		host = "127.0.0.1"
+	case "localhost":
+	default:
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return "", errors.New("Admin Addr Must Be Localhost: " + addr)
+		}
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+func _WriteAdminJson(resp http.ResponseWriter, v interface{}) {
+	resp.Header().Set("Content-Type", "application/json; charset=utf-8")
+	encoder := json.NewEncoder(resp)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
+
+func _AdminSeconds(req *http.Request, def int) time.Duration {
+	sec, err := strconv.Atoi(req.FormValue("seconds"))
+	if err != nil || sec <= 0 {
+		sec = def
+	}
+	return time.Duration(sec) * time.Second
+}
+
+// _AdminPprof 基于 runtime/pprof 实现，避免引入 net/http/pprof 向 http.DefaultServeMux 注册路由
+func _AdminPprof(resp http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(req.URL.Path, AdminPprofPath)
+	switch name {
+	case "":
+		resp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, p := range pprof.Profiles() {
+			_, _ = fmt.Fprintf(resp, "%d\t%s\n", p.Count(), p.Name())
+		}
+		_, _ = fmt.Fprintf(resp, "-\tprofile\n-\ttrace\n-\tcmdline\n")
+	case "cmdline":
+		resp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = fmt.Fprint(resp, strings.Join(os.Args, "\x00"))
+	case "profile":
+		resp.Header().Set("Content-Type", "application/octet-stream")
+		if err := pprof.StartCPUProfile(resp); err != nil {
+			http.Error(resp, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		select {
+		case <-time.After(_AdminSeconds(req, 30)):
+		case <-req.Context().Done():
+		}
+		pprof.StopCPUProfile()
+	case "trace":
+		resp.Header().Set("Content-Type", "application/octet-stream")
+		if err := trace.Start(resp); err != nil {
+			http.Error(resp, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		select {
+		case <-time.After(_AdminSeconds(req, 1)):
+		case <-req.Context().Done():
+		}
+		trace.Stop()
+	default:
+		p := pprof.Lookup(name)
+		if p == nil {
+			http.NotFound(resp, req)
+			return
+		}
+		debug, _ := strconv.Atoi(req.FormValue("debug"))
+		if debug > 0 {
+			resp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		} else {
+			resp.Header().Set("Content-Type", "application/octet-stream")
+		}
+		_ = p.WriteTo(resp, debug)
+	}
+}
+
+func (app *Application) adminStats(resp http.ResponseWriter, _ *http.Request) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	_WriteAdminJson(resp, map[string]interface{}{
+		"uptime":     time.Since(app.startAt).String(),
+		"goroutine":  runtime.NumGoroutine(),
+		"cpu":        runtime.NumCPU(),
+		"gomaxprocs": runtime.GOMAXPROCS(0),
+		"go_version": runtime.Version(),
+		"memory": map[string]interface{}{
+			"alloc":        m.Alloc,
+			"total_alloc":  m.TotalAlloc,
+			"sys":          m.Sys,
+			"heap_alloc":   m.HeapAlloc,
+			"heap_inuse":   m.HeapInuse,
+			"heap_objects": m.HeapObjects,
+			"stack_inuse":  m.StackInuse,
+			"num_gc":       m.NumGC,
+			"pause_total":  time.Duration(m.PauseTotalNs).String(),
+		},
+	})
+}
+
+func (app *Application) adminConfig(resp http.ResponseWriter, _ *http.Request) {
+	_WriteAdminJson(resp, map[string]interface{}{
+		"env":      app.Cfg.EnvDesc(),
+		"version":  app.Cfg.Version(),
+		"exec_dir": app.Cfg.ExecDir(),
+		"log_more": app.Cfg.LogMore(),
+	})
+}
+
+func (app *Application) adminComponent(resp http.ResponseWriter, _ *http.Request) {
+	ret := make([]_AdminComponent, 0, len(app.coms))
+	for _, v := range app.coms {
+		item := _AdminComponent{Name: v.name, State: ComponentStateDesc(v.State())}
+		if d, ok := v.raw.(ComponentDepend); ok {
+			item.Depends = d.Depends()
+		}
+		ret = append(ret, item)
+	}
+	_WriteAdminJson(resp, ret)
+}
+
+// runAdmin 启动管理服务，未配置监听地址时不启动
+func (app *Application) runAdmin() (*http.Server, error) {
+	if app.adminAddr == "" {
+		return nil, nil
+	}
+	// 平滑重启时使用旧进程传递的监听
+	ln := app.adminLn
+	if ln == nil {
+		addr, err := _AdminAddr(app.adminAddr)
+		if err != nil {
+			return nil, err
+		}
+		if ln, err = net.Listen("tcp", addr); err != nil {
+			return nil, err
+		}
+		app.adminLn = ln
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(AdminPprofPath, _AdminPprof)
+	mux.HandleFunc(AdminStatsPath, app.adminStats)
+	mux.HandleFunc(AdminConfigPath, app.adminConfig)
+	mux.HandleFunc(AdminComponentPath, app.adminComponent)
+	srv := &http.Server{Handler: mux}
+
+	app.Log.WarnForce("Listening Admin Server http://%s%s\n", ln.Addr().String(), AdminPprofPath)
+	go func() {
+		if err := srv.Serve(ln); err != http.ErrServerClosed {
+			app.Log.ErrorForce("Admin Server Error: %s", err.Error())
+		}
+	}()
+	return srv, nil
+}
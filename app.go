@@ -1,16 +1,18 @@
 package gsf
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/kzangv/gsf-fof/logger"
 	"github.com/urfave/cli/v2"
 	"math/rand"
+	"net"
 	"os"
 	"os/signal"
 	"runtime"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -24,7 +26,10 @@ const (
 	CliAppEnv     = "app-env"
 	CliAppVer     = "app-version"
 	CliAppLogMore = "app-log-more"
+	CliAppTimeout = "app-shutdown-timeout"
 	InvalidPath   = "__invalid__"
+
+	DefaultAppShutdownTimeout = 60
 )
 
 var (
@@ -127,105 +132,334 @@ type Service interface {
 	Close()
 }
 
+// ContextComponent 支持 context 的组件，ctx 在应用退出时被取消
+type ContextComponent interface {
+	CliFlags() []cli.Flag
+	Init(context.Context, logger.Interface, Config) error
+	Run(context.Context, logger.Interface, Config) error
+	Close(context.Context, logger.Interface, Config) error
+}
+
+// ContextService 支持 context 的服务，ctx 在应用退出时被取消
+type ContextService interface {
+	CliFlags() []cli.Flag
+	Init(context.Context, *Config, *cli.Context) (logger.Interface, error)
+	Run(context.Context, logger.Interface, *Config) error
+	Close(context.Context)
+}
+
+// WrapComponent 将 Component 适配为 ContextComponent
+type WrapComponent struct {
+	Component
+}
+
+func (w WrapComponent) Init(_ context.Context, l logger.Interface, cfg Config) error {
+	return w.Component.Init(l, cfg)
+}
+func (w WrapComponent) Run(_ context.Context, l logger.Interface, cfg Config) error {
+	return w.Component.Run(l, cfg)
+}
+func (w WrapComponent) Close(_ context.Context, l logger.Interface, cfg Config) error {
+	return w.Component.Close(l, cfg)
+}
+
+// WrapService 将 Service 适配为 ContextService
+type WrapService struct {
+	Service
+}
+
+func (w WrapService) Init(_ context.Context, cfg *Config, ctx *cli.Context) (logger.Interface, error) {
+	return w.Service.Init(cfg, ctx)
+}
+func (w WrapService) Run(_ context.Context, l logger.Interface, cfg *Config) error {
+	return w.Service.Run(l, cfg)
+}
+func (w WrapService) Close(_ context.Context) {
+	w.Service.Close()
+}
+
 type Application struct {
-	Component map[string]Component
-	Log       logger.Interface
-	Cfg       Config
-	Ser       Service
-}
-
-func (app *Application) closeComponent() {
-	if app.Component != nil && len(app.Component) > 0 {
-		wg := sync.WaitGroup{}
-		wg.Add(len(app.Component))
-		for k := range app.Component {
-			go func(v Component) {
-				defer wg.Done()
-				_ = v.Close(app.Log, app.Cfg)
-			}(app.Component[k])
+	Component    map[string]Component
+	CtxComponent map[string]ContextComponent
+	Log          logger.Interface
+	Cfg          Config
+	Ser          Service
+	CtxSer       ContextService
+	Services     map[string]Service        // 命名服务，命令行参数以服务名为前缀
+	CtxServices  map[string]ContextService // 命名服务，命令行参数以服务名为前缀
+	Cmd          *cli.App
+	EnvPrefix    string // 命令行参数绑定的环境变量前缀，默认为 DefaultEnvPrefix
+
+	ctx          context.Context
+	cancel       context.CancelFunc
+	sers         []*_Service
+	coms         []*_Component
+	closeTimeout int
+	running      int32
+	adminAddr    string
+	adminLn      net.Listener
+	startAt      time.Time
+	args         []string
+	upgrading    int32
+	upgradeReady *os.File // 平滑重启时通知旧进程启动完成的管道
+}
+
+// Context 应用根 context，收到退出信号时被取消
+func (app *Application) Context() context.Context {
+	return app.ctx
+}
+
+// ComponentCloseTimeout 组件可选接口，返回组件关闭的超时时间，超时时间同时受应用关闭超时限制
+type ComponentCloseTimeout interface {
+	CloseTimeout() time.Duration
+}
+
+type _CloseReport struct {
+	closed, failed, timeout []string
+	errs                    []error
+}
+
+func (r *_CloseReport) Add(name string, err error) {
+	if err != nil {
+		r.failed = append(r.failed, name)
+		r.errs = append(r.errs, errors.New(name+": "+err.Error()))
+	} else {
+		r.closed = append(r.closed, name)
+	}
+}
+
+func (r *_CloseReport) Timeout(name string) {
+	r.timeout = append(r.timeout, name)
+}
+
+func (r *_CloseReport) String() string {
+	return fmt.Sprintf("Application Close Report: closed[%s] failed[%s] timeout[%s]\n",
+		strings.Join(r.closed, ","), strings.Join(r.failed, ","), strings.Join(r.timeout, ","))
+}
+
+func (r *_CloseReport) Error() error {
+	if len(r.errs) == 0 && len(r.timeout) == 0 {
+		return nil
+	}
+	errStr := make([]string, 0, len(r.errs)+1)
+	for k := range r.errs {
+		errStr = append(errStr, r.errs[k].Error())
+	}
+	if len(r.timeout) > 0 {
+		errStr = append(errStr, "timeout: "+strings.Join(r.timeout, ","))
+	}
+	return errors.New("Application Close Error: " + strings.Join(errStr, ","))
+}
+
+func (app *Application) closeComponent(ctx context.Context, report *_CloseReport) {
+	// 超时的组件关闭协程可能在 Run 返回后继续运行，不能再访问 app
+	log, cfg := app.Log, app.Cfg
+	// 按依赖的逆序关闭组件
+	for i := len(app.coms) - 1; i >= 0; i-- {
+		v, cCtx, cancel := app.coms[i], ctx, context.CancelFunc(func() {})
+		if t, ok := v.raw.(ComponentCloseTimeout); ok && t.CloseTimeout() > 0 {
+			cCtx, cancel = context.WithTimeout(ctx, t.CloseTimeout())
 		}
 
-		wg.Wait()
+		done := make(chan error, 1)
+		v.SetState(ComponentClosing, nil)
+		go func() {
+			err := v.com.Close(cCtx, log, cfg)
+			v.SetState(ComponentClosed, err)
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			report.Add(v.name, err)
+		case <-cCtx.Done():
+			report.Timeout(v.name)
+		}
+		cancel()
 	}
 }
 
-func (app *Application) shutdown() {
-	defer func() {
-		fmt.Println("Application Had Exit")
-		os.Exit(0)
-	}()
-	fmt.Println("Application To Exit")
+// HealthChecker 组件可选接口，用于应用就绪检查
+type HealthChecker interface {
+	HealthCheck() error
 }
 
-func (app *Application) catchSignal() {
-	c := make(chan os.Signal)
-	signal.Notify(c, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+// ReadyBinder 服务可选接口，启动时绑定应用的就绪检查
+type ReadyBinder interface {
+	BindReady(func() error)
+}
 
-	for s := range c {
-		switch s {
-		case syscall.SIGHUP:
-			fallthrough
-		case syscall.SIGINT, syscall.SIGTERM:
-			app.closeComponent()
-			app.Ser.Close()
-			app.shutdown()
+// Ready 应用就绪检查，汇总组件健康检查结果，开始关闭后即返回错误
+func (app *Application) Ready() error {
+	if atomic.LoadInt32(&app.running) == 0 || app.ctx.Err() != nil {
+		return errors.New("Application Is Not Running")
+	}
+	var errStr []string
+	for _, v := range app.coms {
+		if h, ok := v.raw.(HealthChecker); ok {
+			if err := h.HealthCheck(); err != nil {
+				errStr = append(errStr, v.name+": "+err.Error())
+			}
 		}
 	}
+	if len(errStr) > 0 {
+		return errors.New("Component Health Error: " + strings.Join(errStr, ","))
+	}
+	return nil
 }
 
-func (app *Application) runComponent() error {
-	if app.Component != nil && len(app.Component) > 0 {
-		wg := sync.WaitGroup{}
-		lock := sync.Mutex{}
-		var errList []error
-
-		wg.Add(len(app.Component))
-		for k := range app.Component {
-			go func(v Component) {
-				defer wg.Done()
-				err := v.Run(app.Log, app.Cfg)
-				if err != nil {
-					lock.Lock()
-					if errList == nil {
-						errList = make([]error, 0)
-					}
-					errList = append(errList, err)
-					lock.Unlock()
-				}
-			}(app.Component[k])
+// Reloader 组件及服务可选接口，收到 SIGHUP 信号时按依赖顺序重新加载配置
+type Reloader interface {
+	Reload(logger.Interface, Config) error
+}
+
+func (app *Application) reload() {
+	app.Log.WarnForce("Application Reload\n")
+	for _, v := range app.coms {
+		if r, ok := v.raw.(Reloader); ok {
+			if err := r.Reload(app.Log, app.Cfg); err != nil {
+				app.Log.ErrorForce("Component Reload Error: %s: %s", v.name, err.Error())
+			}
 		}
+	}
+	for _, v := range app.sers {
+		if r, ok := v.raw.(Reloader); ok {
+			if err := r.Reload(v.log, app.Cfg); err != nil {
+				app.Log.ErrorForce("Service Reload Error: %s: %s", v.name, err.Error())
+			}
+		}
+	}
+}
+
+// _NotifySignal 注册需要处理的信号，在启动服务前调用，避免启动过程中收到的信号丢失
+func _NotifySignal() chan os.Signal {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	if _UpgradeSignal != nil {
+		signal.Notify(c, _UpgradeSignal)
+	}
+	return c
+}
 
-		wg.Wait()
-		if len(errList) > 0 {
-			errStr := make([]string, 0, len(errList))
-			for k := range errList {
-				errStr = append(errStr, errList[k].Error())
+// catchSignal 处理信号直到 stop 关闭；开始关闭后忽略信号，关闭完成前保持注册，避免 SIGHUP 等按默认处理终止进程
+func (app *Application) catchSignal(c chan os.Signal, stop <-chan struct{}) {
+	for {
+		select {
+		case s := <-c:
+			if app.ctx.Err() != nil {
+				app.Log.WarnForce("Application Is Closing, Ignore Signal: %s\n", s.String())
+				continue
 			}
-			return errors.New("Component Run Error:" + strings.Join(errStr, ","))
+			switch s {
+			case syscall.SIGHUP:
+				app.reload()
+			case syscall.SIGINT, syscall.SIGTERM:
+				// 通知组件及服务退出
+				app.cancel()
+			case _UpgradeSignal:
+				app.upgrade()
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (app *Application) runComponent() error {
+	for _, v := range app.coms {
+		err := v.com.Run(app.ctx, app.Log, app.Cfg)
+		v.SetState(ComponentRunning, err)
+		if err != nil {
+			return errors.New("Component Run Error: " + v.name + ": " + err.Error())
 		}
 	}
 	return nil
 }
 
 func (app *Application) run() error {
-	var err error = nil
-
 	runtime.GOMAXPROCS(runtime.NumCPU())
-	go app.catchSignal()
+	// 信号处理持续到组件及服务关闭完成
+	c, stop := _NotifySignal(), make(chan struct{})
+	defer func() {
+		signal.Stop(c)
+		close(stop)
+	}()
+	go app.catchSignal(c, stop)
 
 	// 初始化随机种子
 	rand.Seed(time.Now().UnixNano())
 
+	// 启动管理服务
+	app.startAt = time.Now()
+	admin, err := app.runAdmin()
+	if admin != nil {
+		defer admin.Close()
+	}
+
 	// 启动服务
-	err = app.runComponent()
-	if err != nil {
-		return err
+	var pending map[*_Service]bool
+	done := make(chan _ServiceResult, len(app.sers))
+	if err == nil {
+		err = app.runComponent()
 	}
+	if err == nil {
+		atomic.StoreInt32(&app.running, 1)
+		defer atomic.StoreInt32(&app.running, 0)
+
+		// 平滑重启启动的新进程，服务完成监听后通知旧进程退出
+		go app.notifyUpgrade()
 
-	return app.Ser.Run(app.Log, &app.Cfg)
+		// 任一服务出错时，关闭其他服务
+		pending, err = app.runService(done)
+		if len(pending) > 0 {
+			fmt.Println("Application To Exit")
+		}
+	}
+	app.cancel()
+
+	// 关闭超时，小于等于 0 时使用默认值
+	timeout := app.closeTimeout
+	if timeout <= 0 {
+		timeout = DefaultAppShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	// 先关闭服务等待请求处理完成，再关闭服务依赖的组件
+	report := _CloseReport{}
+	if cErr := app.closeService(ctx, &report, pending, done); err == nil {
+		err = cErr
+	}
+	app.closeComponent(ctx, &report)
+	app.Log.WarnForce(report.String())
+	if err == nil {
+		err = report.Error()
+	}
+	fmt.Println("Application Had Exit")
+	return err
 }
 
+// Start 启动应用，出错时以状态码 1 退出进程
 func (app *Application) Start(cmd *cli.App, args []string, service Service) {
+	app.Cmd, app.Ser = cmd, service
+	app.exit(app.Run(context.Background(), args))
+}
+
+// StartContext 同 Start，服务为 ContextService
+func (app *Application) StartContext(cmd *cli.App, args []string, service ContextService) {
+	app.Cmd, app.CtxSer = cmd, service
+	app.exit(app.Run(context.Background(), args))
+}
+
+func (app *Application) exit(err error) {
+	if err != nil {
+		fmt.Printf("Init Error: %s", err.Error())
+		os.Exit(1)
+	}
+}
+
+// Run 启动应用并阻塞，直到服务结束、收到退出信号或 ctx 被取消，完成优雅退出后返回
+func (app *Application) Run(ctx context.Context, args []string) error {
+	cmd := app.Cmd
 	if cmd == nil {
 		cmd = cli.NewApp()
 		cmd.Version = "1.0"
@@ -234,28 +468,55 @@ func (app *Application) Start(cmd *cli.App, args []string, service Service) {
 		cmd.DisableSliceFlagSeparator = true
 	}
 
-	app.Ser = service
+	app.ctx, app.cancel = context.WithCancel(ctx)
+	defer app.cancel()
+	// 服务
+	var err error
+	if app.sers, err = loadService(app.Ser, app.CtxSer, app.Services, app.CtxServices); err != nil {
+		return err
+	}
+
+	// 组件依赖排序
+	if app.coms, err = loadComponent(app.Component, app.CtxComponent); err != nil {
+		return err
+	}
 
 	// app
-	cfs := make([][]cli.Flag, 0, len(app.Component)+2)
-	cfs = append(cfs, []cli.Flag{
+	group := &_FlagGroup{
+		services:  make(map[string][]cli.Flag, len(app.sers)),
+		component: make(map[string][]cli.Flag, len(app.coms)),
+	}
+	group.app = []cli.Flag{
 		// base
 		&cli.StringFlag{Name: CliAppEnv, Usage: app.Cfg.env.Usage(), Action: app.Cfg.env.Action},
 		&cli.StringFlag{Name: CliAppVer, Value: EnvVersion, Usage: "version", Destination: &app.Cfg.version},
 		&cli.BoolFlag{Name: CliAppLogMore, Value: false, Usage: "log more", Destination: &app.Cfg.logMore},
-	})
+		&cli.IntFlag{Name: CliAppTimeout, Value: DefaultAppShutdownTimeout, Usage: "app shutdown timeout, 0 is default", Destination: &app.closeTimeout},
+		&cli.StringFlag{Name: CliAppConfig, Usage: "config file(yaml/json)"},
+		&cli.StringFlag{Name: CliAppAdminAddr, Value: "", Usage: "admin server addr(pprof, stats, config, components), localhost only, empty is off", Destination: &app.adminAddr},
+	}
+	cfs := make([][]cli.Flag, 0, len(app.coms)+len(app.sers)+1)
+	cfs = append(cfs, group.app)
 	fsLen := len(cfs[0])
 
 	// service
-	afs := app.Ser.CliFlags()
-	if len(afs) > 0 {
-		cfs = append(cfs, afs)
-		fsLen += len(afs)
+	for _, s := range app.sers {
+		v := s.CliFlags()
+		if s.name == _ServiceName {
+			group.service = v
+		} else {
+			group.services[s.name] = v
+		}
+		if len(v) > 0 {
+			cfs = append(cfs, v)
+			fsLen += len(v)
+		}
 	}
 
 	// component
-	for k := range app.Component {
-		v := app.Component[k].CliFlags()
+	for _, c := range app.coms {
+		v := c.com.CliFlags()
+		group.component[c.name] = v
 		if len(v) > 0 {
 			cfs = append(cfs, v)
 			fsLen += len(v)
@@ -268,19 +529,57 @@ func (app *Application) Start(cmd *cli.App, args []string, service Service) {
 		fs = append(fs, v...)
 	}
 
+	// 绑定环境变量，优先级：命令行 > 环境变量 > 配置文件 > 默认值
+	prefix := app.EnvPrefix
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+	_BindFlagEnv(prefix, fs)
+
+	// 配置文件在命令行参数解析后、参数 Action 执行前加载
+	before := cmd.Before
+	cmd.Before = func(ctx *cli.Context) error {
+		if err := app.loadConfig(ctx, group); err != nil {
+			return err
+		}
+		if before != nil {
+			return before(ctx)
+		}
+		return nil
+	}
+
 	// 执行命令行
 	cmd.Flags = fs
 	cmd.Action = func(ctx *cli.Context) error {
 		var err error = nil
 		app.Cfg.execDir, err = os.Getwd()
 		if err == nil {
-			// 用命令行初始化配置
-			app.Log, err = app.Ser.Init(&app.Cfg, ctx)
+			// 平滑重启时使用旧进程传递的监听
+			if err = app.inheritListener(); err != nil {
+				return err
+			}
+			// 用命令行初始化配置，应用使用第一个服务的日志
+			for k, v := range app.sers {
+				if r, ok := v.raw.(ReadyBinder); ok {
+					r.BindReady(app.Ready)
+				}
+				if v.log, err = v.ser.Init(app.ctx, &app.Cfg, ctx); err != nil {
+					if len(app.sers) > 1 {
+						err = errors.New("Service Init Error: " + v.name + ": " + err.Error())
+					}
+					break
+				}
+				if k == 0 {
+					app.Log = v.log
+				}
+			}
 			if err == nil {
 				// 组件初始化
-				for k := range app.Component {
-					if err = app.Component[k].Init(app.Log, app.Cfg); err != nil {
-						err = errors.New("Component Init Error: " + err.Error())
+				for _, v := range app.coms {
+					err = v.com.Init(app.ctx, app.Log, app.Cfg)
+					v.SetState(ComponentInit, err)
+					if err != nil {
+						err = errors.New("Component Init Error: " + v.name + ": " + err.Error())
 						break
 					}
 				}
@@ -295,9 +594,6 @@ func (app *Application) Start(cmd *cli.App, args []string, service Service) {
 		return err
 	}
 
-	err := cmd.Run(args)
-	if err != nil {
-		fmt.Printf("Init Error: %s", err.Error())
-		os.Exit(1)
-	}
+	app.args = args
+	return cmd.RunContext(app.ctx, args)
 }
@@ -1,10 +1,22 @@
 package gsf
 
 import (
+	"context"
+	"errors"
 	"github.com/kzangv/gsf-fof/logger"
 	"github.com/urfave/cli/v2"
+	"io"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
 )
 
 type _TestComponent struct {
@@ -46,11 +58,20 @@ func TestWeb(t *testing.T) {
 			"test": &_TestComponent{},
 		},
 	}
-	app.Start(
-		nil,
-		[]string{"test", "--app-env=local", "--web-port=8888", "--web-r-timeout=10", "--web-w-timeout=9", "--web-idle-timeout=8"},
-		&WebService{Handler: &_WebRouter{app: &app}},
-	)
+	ser := &WebService{Handler: &_WebRouter{app: &app}}
+	app.Ser = ser
+
+	args := []string{"--app-env=local", "--web-ip=127.0.0.1", "--web-port=0", "--web-r-timeout=10", "--web-w-timeout=9", "--web-idle-timeout=8"}
+	_RunTestApp(t, &app, ser, args, func(client *http.Client, addr net.Addr) {
+		resp, err := client.Get("http://" + addr.String() + "/ping")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		t.Logf("### response: %s", body)
+	})
 }
 
 func TestCmd(t *testing.T) {
@@ -74,3 +95,399 @@ func TestCmd(t *testing.T) {
 		cmd,
 	)
 }
+
+type _TestDependComponent struct {
+	_TestComponent
+	depends []string
+}
+
+func (c *_TestDependComponent) Depends() []string {
+	return c.depends
+}
+
+func TestComponentOrder(t *testing.T) {
+	order, err := sortComponent(map[string]interface{}{
+		"cache": &_TestDependComponent{depends: []string{"db"}},
+		"api":   &_TestDependComponent{depends: []string{"cache", "db"}},
+		"db":    &_TestComponent{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(order, ",") != "db,cache,api" {
+		t.Errorf("order: %v", order)
+	}
+
+	if _, err = sortComponent(map[string]interface{}{
+		"cache": &_TestDependComponent{depends: []string{"db"}},
+	}); err == nil {
+		t.Error("missing depend should fail")
+	}
+
+	if _, err = sortComponent(map[string]interface{}{
+		"a": &_TestDependComponent{depends: []string{"b"}},
+		"b": &_TestDependComponent{depends: []string{"a"}},
+	}); err == nil {
+		t.Error("cycle depend should fail")
+	} else {
+		t.Log(err)
+	}
+}
+
+type _TestHangComponent struct {
+	_TestComponent
+}
+
+func (c *_TestHangComponent) CliFlags() []cli.Flag {
+	return nil
+}
+
+func (c *_TestHangComponent) Close(_ logger.Interface, _ Config) error {
+	time.Sleep(time.Second * 10)
+	return nil
+}
+
+func (c *_TestHangComponent) CloseTimeout() time.Duration {
+	return time.Millisecond * 100
+}
+
+func TestCloseTimeout(t *testing.T) {
+	app := Application{
+		Component: map[string]Component{
+			"test": &_TestComponent{},
+			"hang": &_TestHangComponent{},
+		},
+	}
+	cmd := &CmdService{}
+	cmd.AddCmdFunc("do", func(log logger.Interface) error { return nil })
+	app.Ser = cmd
+
+	begin := time.Now()
+	err := app.Run(context.Background(), []string{"test", "--app-cmd=do"})
+	if err == nil || !strings.Contains(err.Error(), "hang") {
+		t.Fatalf("close timeout should be reported: %v", err)
+	}
+	if time.Since(begin) > time.Second {
+		t.Errorf("close should not wait for hang component")
+	}
+
+	// 关闭超时为 0 时使用默认值，组件正常关闭
+	app2 := Application{Component: map[string]Component{"test": &_TestComponent{}}, Ser: cmd}
+	if err = app2.Run(context.Background(), []string{"test", "--app-cmd=do", "--app-shutdown-timeout=0"}); err != nil {
+		t.Errorf("zero close timeout should use default: %v", err)
+	}
+}
+
+type _TestReloadComponent struct {
+	_TestComponent
+	reload int32
+}
+
+func (c *_TestReloadComponent) CliFlags() []cli.Flag {
+	return nil
+}
+
+func (c *_TestReloadComponent) Reload(_ logger.Interface, _ Config) error {
+	atomic.AddInt32(&c.reload, 1)
+	return nil
+}
+
+func TestReload(t *testing.T) {
+	com := &_TestReloadComponent{}
+	ser := &WebService{Handler: http.NotFoundHandler()}
+	app := Application{
+		Component: map[string]Component{
+			"reload": com,
+		},
+		Ser: ser,
+	}
+
+	// 服务开始监听时信号已注册
+	_RunTestApp(t, &app, ser, []string{"--web-ip=127.0.0.1", "--web-port=0"}, func(*http.Client, net.Addr) {
+		p, _ := os.FindProcess(os.Getpid())
+		_ = p.Signal(syscall.SIGHUP)
+		for i := 0; i < 100 && atomic.LoadInt32(&com.reload) == 0; i++ {
+			time.Sleep(time.Millisecond * 10)
+		}
+	})
+	if atomic.LoadInt32(&com.reload) != 1 {
+		t.Errorf("component should be reloaded once")
+	}
+}
+
+type _TestSignalComponent struct {
+	_TestReloadComponent
+}
+
+func (c *_TestSignalComponent) Close(_ logger.Interface, _ Config) error {
+	// 等待信号处理协程观察到开始关闭
+	time.Sleep(time.Millisecond * 50)
+	p, _ := os.FindProcess(os.Getpid())
+	_ = p.Signal(syscall.SIGHUP)
+	time.Sleep(time.Millisecond * 100)
+	return nil
+}
+
+func TestSignalOnClose(t *testing.T) {
+	com := &_TestSignalComponent{}
+	cmd := &CmdService{}
+	cmd.AddCmdFunc("do", func(log logger.Interface) error { return nil })
+	app := Application{Component: map[string]Component{"signal": com}, Ser: cmd}
+
+	// 关闭过程中收到 SIGHUP 不重新加载，也不终止进程
+	if err := app.Run(context.Background(), []string{"test", "--app-cmd=do"}); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&com.reload) != 0 {
+		t.Errorf("component should not be reloaded on close")
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	err := os.WriteFile(path, []byte(`
+app:
+  app-version: v3.0
+  app-log-more: false
+services:
+  admin:
+    web-port: 1
+component:
+  test:
+    test-name: file
+env:
+  release:
+    app:
+      app-log-more: true
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 命名服务使用指定的监听，只检查配置是否生效
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	com, admin := &_TestComponent{}, &WebService{Handler: http.NotFoundHandler(), Listener: ln}
+	app := Application{
+		Component: map[string]Component{
+			"test": com,
+		},
+		Services: map[string]Service{
+			"admin": admin,
+		},
+	}
+	// 命令执行完成后退出应用
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := &CmdService{}
+	cmd.AddCmdFunc("do", func(log logger.Interface) error { cancel(); return nil })
+	app.Ser = cmd
+
+	err = app.Run(ctx, []string{"test", "--app-env=release", "--app-config=" + path, "--app-version=cli", "--app-cmd=do"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.Cfg.Version() != "cli" || !app.Cfg.LogMore() || com.name != "file" || admin.Cfg.Port != 1 {
+		t.Errorf("config merge fail: version=%s log-more=%t name=%s admin-port=%d", app.Cfg.Version(), app.Cfg.LogMore(), com.name, admin.Cfg.Port)
+	}
+}
+
+func TestFlagEnv(t *testing.T) {
+	t.Setenv("TEST_APP_VERSION", "env")
+	t.Setenv("TEST_TEST_NAME", "env-name")
+
+	com := &_TestComponent{}
+	app := Application{
+		Component: map[string]Component{
+			"test": com,
+		},
+		EnvPrefix: "TEST",
+	}
+	cmd := &CmdService{}
+	cmd.AddCmdFunc("do", func(log logger.Interface) error { return nil })
+	app.Ser = cmd
+
+	if err := app.Run(context.Background(), []string{"test", "--app-cmd=do"}); err != nil {
+		t.Fatal(err)
+	}
+	if app.Cfg.Version() != "env" || com.name != "env-name" {
+		t.Errorf("env bind fail: version=%s name=%s", app.Cfg.Version(), com.name)
+	}
+}
+
+type _TestHealthComponent struct {
+	_TestComponent
+	err error
+}
+
+func (c *_TestHealthComponent) CliFlags() []cli.Flag {
+	return nil
+}
+
+func (c *_TestHealthComponent) HealthCheck() error {
+	return c.err
+}
+
+func TestHealth(t *testing.T) {
+	com := &_TestHealthComponent{}
+	ser := &WebService{Handler: http.NotFoundHandler()}
+	app := Application{
+		Component: map[string]Component{
+			"health": com,
+		},
+		Ser: ser,
+	}
+
+	args := []string{"--web-ip=127.0.0.1", "--web-port=0", "--web-health", "--web-health-addr=127.0.0.1:0"}
+	_RunTestApp(t, &app, ser, args, func(client *http.Client, _ net.Addr) {
+		get := func(path string) int {
+			resp, err := client.Get("http://" + ser.healthLn.Addr().String() + path)
+			if err != nil {
+				t.Error(err)
+				return 0
+			}
+			_ = resp.Body.Close()
+			return resp.StatusCode
+		}
+		if code := get(WebHealthPath); code != http.StatusOK {
+			t.Errorf("healthz: %d", code)
+		}
+		if code := get(WebReadyPath); code != http.StatusOK {
+			t.Errorf("readyz: %d", code)
+		}
+		com.err = errors.New("db down")
+		if code := get(WebReadyPath); code != http.StatusServiceUnavailable {
+			t.Errorf("readyz should fail: %d", code)
+		}
+	})
+}
+
+func TestAdmin(t *testing.T) {
+	if _, err := _AdminAddr("0.0.0.0:8892"); err == nil {
+		t.Error("admin addr should be localhost only")
+	}
+
+	ser := &WebService{Handler: http.NotFoundHandler()}
+	app := Application{
+		Component: map[string]Component{
+			"test": &_TestComponent{},
+		},
+		Ser: ser,
+	}
+
+	_RunTestApp(t, &app, ser, []string{"--web-ip=127.0.0.1", "--web-port=0", "--app-admin-addr=:0"}, func(client *http.Client, _ net.Addr) {
+		for _, path := range []string{AdminComponentPath, AdminConfigPath, AdminStatsPath, AdminPprofPath + "goroutine?debug=1"} {
+			resp, err := client.Get("http://" + app.adminLn.Addr().String() + path)
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("%s: %d", path, resp.StatusCode)
+			}
+			if path == AdminComponentPath && !strings.Contains(string(body), `"running"`) {
+				t.Errorf("component state: %s", body)
+			}
+		}
+	})
+}
+
+func TestMultiService(t *testing.T) {
+	web, admin, worker := &WebService{Handler: http.NotFoundHandler()}, &WebService{Handler: http.NotFoundHandler()}, &CmdService{}
+	worker.AddCmdFunc("do", func(log logger.Interface) error {
+		for admin.Addr() == nil || web.Addr() == nil {
+			time.Sleep(time.Millisecond * 10)
+		}
+		return errors.New("worker fail")
+	})
+	app := Application{
+		Ser: web,
+		Services: map[string]Service{
+			"admin":  admin,
+			"worker": worker,
+		},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "--web-ip=127.0.0.1", "--web-port=0", "--admin-web-ip=127.0.0.1", "--admin-web-port=0", "--worker-app-cmd=do"})
+	if err == nil || !strings.Contains(err.Error(), "worker fail") {
+		t.Fatalf("worker error should stop application: %v", err)
+	}
+	if admin.Addr().String() == web.Addr().String() {
+		t.Errorf("service flags should be namespaced")
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("upgrade is not supported on windows")
+	}
+	args := []string{"test", "--web-ip=127.0.0.1", "--web-port=0", "--web-health", "--web-health-addr=127.0.0.1:0", "--app-admin-addr=:0"}
+	newApp := func() (*Application, *WebService) {
+		ser := &WebService{Handler: http.HandlerFunc(func(resp http.ResponseWriter, _ *http.Request) {
+			_, _ = resp.Write([]byte(strconv.Itoa(os.Getpid())))
+		})}
+		return &Application{Component: map[string]Component{"test": &_TestComponent{}}, Ser: ser}, ser
+	}
+
+	// 新进程：使用旧进程传递的监听运行一段时间后退出
+	if os.Getenv("GSF_TEST_UPGRADE") == "child" {
+		app, _ := newApp()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+		defer cancel()
+		if err := app.Run(ctx, args); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	t.Setenv("GSF_TEST_UPGRADE", "child")
+	exec := _UpgradeExec
+	defer func() { _UpgradeExec = exec }()
+	_UpgradeExec = func([]string) (string, []string, error) {
+		exe, err := os.Executable()
+		return exe, []string{"-test.run=^TestUpgrade$"}, err
+	}
+
+	app, ser := newApp()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	urls := make(chan []string, 1)
+	go func() {
+		for ser.Addr() == nil {
+			time.Sleep(time.Millisecond * 10)
+		}
+		// 新进程继承监听，地址不变
+		urls <- []string{"http://" + ser.Addr().String() + "/", "http://" + ser.healthLn.Addr().String() + WebHealthPath,
+			"http://" + app.adminLn.Addr().String() + AdminComponentPath}
+		p, _ := os.FindProcess(os.Getpid())
+		_ = p.Signal(_UpgradeSignal)
+	}()
+	if err := app.Run(ctx, args); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("old process should exit after new process is ready")
+	}
+
+	// 旧进程退出后，服务、健康检查及管理服务由新进程提供
+	webURLs := <-urls
+	for _, url := range webURLs {
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: %d", url, resp.StatusCode)
+		}
+		if url == webURLs[0] && string(body) == strconv.Itoa(os.Getpid()) {
+			t.Errorf("request should be served by new process")
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package gsf
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// 组件生命周期状态
+const (
+	ComponentCreated = iota
+	ComponentInit
+	ComponentRunning
+	ComponentClosing
+	ComponentClosed
+	ComponentFailed
+)
+
+const (
+	_SortUnVisit = iota
+	_SortVisiting
+	_SortVisited
+)
+
+// ComponentDepend 组件可选接口，返回所依赖的组件名称
+// 被依赖的组件先初始化、先运行，后关闭
+type ComponentDepend interface {
+	Depends() []string
+}
+
+type _Component struct {
+	name  string
+	raw   interface{} // 原始组件，用于检测可选接口
+	com   ContextComponent
+	state int32
+}
+
+func (c *_Component) SetState(state int, err error) {
+	if err != nil {
+		state = ComponentFailed
+	}
+	atomic.StoreInt32(&c.state, int32(state))
+}
+
+func (c *_Component) State() int {
+	return int(atomic.LoadInt32(&c.state))
+}
+
+// ComponentStateDesc 组件生命周期状态描述
+func ComponentStateDesc(state int) string {
+	switch state {
+	case ComponentCreated:
+		return "created"
+	case ComponentInit:
+		return "init"
+	case ComponentRunning:
+		return "running"
+	case ComponentClosing:
+		return "closing"
+	case ComponentClosed:
+		return "closed"
+	case ComponentFailed:
+		return "failed"
+	}
+	return ""
+}
+
+// loadComponent 合并普通组件与 context 组件，并按依赖关系排序
+func loadComponent(coms map[string]Component, ctxComs map[string]ContextComponent) ([]*_Component, error) {
+	items := make(map[string]*_Component, len(coms)+len(ctxComs))
+	raws := make(map[string]interface{}, len(coms)+len(ctxComs))
+	for k, v := range coms {
+		items[k], raws[k] = &_Component{name: k, raw: v, com: WrapComponent{v}}, v
+	}
+	for k, v := range ctxComs {
+		if _, ok := items[k]; ok {
+			return nil, errors.New("Component Name Repeat: " + k)
+		}
+		items[k], raws[k] = &_Component{name: k, raw: v, com: v}, v
+	}
+
+	order, err := sortComponent(raws)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*_Component, 0, len(order))
+	for _, name := range order {
+		ret = append(ret, items[name])
+	}
+	return ret, nil
+}
+
+// sortComponent 按依赖关系对组件进行拓扑排序，依赖缺失或循环依赖时返回错误
+func sortComponent(coms map[string]interface{}) ([]string, error) {
+	names := make([]string, 0, len(coms))
+	for k := range coms {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	order := make([]string, 0, len(coms))
+	state := make(map[string]int, len(coms))
+	path := make([]string, 0, len(coms))
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case _SortVisited:
+			return nil
+		case _SortVisiting:
+			return errors.New("Component Depend Cycle: " + strings.Join(append(path, name), " -> "))
+		}
+		state[name] = _SortVisiting
+		path = append(path, name)
+
+		if v, ok := coms[name].(ComponentDepend); ok {
+			for _, dep := range v.Depends() {
+				if _, ok := coms[dep]; !ok {
+					return errors.New("Component Depend Missing: " + name + " -> " + dep)
+				}
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = _SortVisited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
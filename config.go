@@ -0,0 +1,232 @@
+package gsf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+const (
+	CliAppConfig = "app-config"
+
+	DefaultEnvPrefix = "GSF"
+)
+
+// _ConfigSection 配置文件中各部分的参数，key 为命令行参数名
+type _ConfigSection struct {
+	App       map[string]interface{}            `json:"app"       yaml:"app"`
+	Service   map[string]interface{}            `json:"service"   yaml:"service"`
+	Services  map[string]map[string]interface{} `json:"services"  yaml:"services"`
+	Component map[string]map[string]interface{} `json:"component" yaml:"component"`
+}
+
+func (s *_ConfigSection) Merge(v *_ConfigSection) {
+	s.App = _MergeConfigValue(s.App, v.App)
+	s.Service = _MergeConfigValue(s.Service, v.Service)
+	s.Services = _MergeConfigSection(s.Services, v.Services)
+	s.Component = _MergeConfigSection(s.Component, v.Component)
+}
+
+// _ConfigFile 配置文件，Env 以环境名(local/test/preview/release)为 key 覆盖基础配置
+type _ConfigFile struct {
+	_ConfigSection `yaml:",inline"`
+	Env            map[string]*_ConfigSection `json:"env" yaml:"env"`
+}
+
+type _FlagGroup struct {
+	app, service        []cli.Flag
+	services, component map[string][]cli.Flag
+}
+
+func _MergeConfigValue(dst, src map[string]interface{}) map[string]interface{} {
+	if len(src) > 0 && dst == nil {
+		dst = make(map[string]interface{}, len(src))
+	}
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
+
+// FlagEnvName 命令行参数对应的环境变量名，如 web-port => GSF_WEB_PORT
+func FlagEnvName(prefix, name string) string {
+	name = strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+	if prefix == "" {
+		return name
+	}
+	return prefix + "_" + name
+}
+
+// _BindFlagEnv 为命令行参数绑定环境变量，参数已声明的环境变量保持不变
+func _BindFlagEnv(prefix string, fs []cli.Flag) {
+	for _, f := range fs {
+		v := reflect.ValueOf(f)
+		if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+			continue
+		}
+		ev := v.Elem().FieldByName("EnvVars")
+		if !ev.IsValid() || !ev.CanSet() || ev.Type() != reflect.TypeOf([]string{}) {
+			continue
+		}
+
+		env := FlagEnvName(prefix, f.Names()[0])
+		envs := ev.Interface().([]string)
+		for _, e := range envs {
+			if e == env {
+				env = ""
+				break
+			}
+		}
+		if env != "" {
+			ev.Set(reflect.ValueOf(append(envs, env)))
+		}
+	}
+}
+
+func _MergeConfigSection(dst, src map[string]map[string]interface{}) map[string]map[string]interface{} {
+	if len(src) > 0 && dst == nil {
+		dst = make(map[string]map[string]interface{}, len(src))
+	}
+	for name, vs := range src {
+		dst[name] = _MergeConfigValue(dst[name], vs)
+	}
+	return dst
+}
+
+func _LoadConfigFile(path string) (*_ConfigFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &_ConfigFile{}
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(ret)
+	} else {
+		err = yaml.Unmarshal(data, ret)
+	}
+	if err != nil {
+		return nil, errors.New("Config File Invalid: " + err.Error())
+	}
+	return ret, nil
+}
+
+// _ConfigEnv 确定配置文件使用的环境，命令行参数优先
+func _ConfigEnv(ctx *cli.Context, file *_ConfigFile) (string, error) {
+	env := EnvLocalArg
+	if ctx.IsSet(CliAppEnv) {
+		env = ctx.String(CliAppEnv)
+	} else if v, ok := file.App[CliAppEnv]; ok {
+		env = fmt.Sprint(v)
+	}
+
+	var f _EnvFlag
+	if err := f.Action(ctx, env); err != nil {
+		return "", err
+	}
+	return env, nil
+}
+
+// _SetFlagValue 用配置填充参数，参数名为 prefix + key，命名服务的配置 key 不含服务名前缀
+func _SetFlagValue(ctx *cli.Context, fs []cli.Flag, section, prefix string, values map[string]interface{}) error {
+	for key, value := range values {
+		var flag cli.Flag
+		fName := prefix + key
+		for _, f := range fs {
+			for _, name := range f.Names() {
+				if name == fName {
+					flag = f
+				}
+			}
+		}
+		if flag == nil {
+			return errors.New("Config Flag Not Find: " + section + "." + key)
+		}
+		// 命令行指定的参数优先
+		if ctx.IsSet(fName) {
+			continue
+		}
+
+		var vs []interface{}
+		switch v := value.(type) {
+		case []interface{}:
+			vs = v
+		default:
+			vs = []interface{}{v}
+		}
+		for _, v := range vs {
+			sv := ""
+			switch v.(type) {
+			case map[string]interface{}:
+				bv, err := json.Marshal(v)
+				if err != nil {
+					return err
+				}
+				sv = string(bv)
+			default:
+				sv = fmt.Sprint(v)
+			}
+			if err := ctx.Set(fName, sv); err != nil {
+				return errors.New("Config Flag Invalid: " + section + "." + key + ": " + err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// loadConfig 加载配置文件，将未在命令行中指定的参数用配置文件中的值填充
+func (app *Application) loadConfig(ctx *cli.Context, group *_FlagGroup) error {
+	path := ctx.String(CliAppConfig)
+	if path == "" {
+		return nil
+	}
+	file, err := _LoadConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	// 环境覆盖配置
+	env, err := _ConfigEnv(ctx, file)
+	if err != nil {
+		return err
+	}
+	cfg := file._ConfigSection
+	if v, ok := file.Env[env]; ok && v != nil {
+		cfg.Merge(v)
+	}
+
+	if err = _SetFlagValue(ctx, group.app, "app", "", cfg.App); err != nil {
+		return err
+	}
+	if err = _SetFlagValue(ctx, group.service, "service", "", cfg.Service); err != nil {
+		return err
+	}
+	for name, values := range cfg.Services {
+		fs, ok := group.services[name]
+		if !ok {
+			return errors.New("Config Service Not Find: " + name)
+		}
+		if err = _SetFlagValue(ctx, fs, "services."+name, name+"-", values); err != nil {
+			return err
+		}
+	}
+	for name, values := range cfg.Component {
+		fs, ok := group.component[name]
+		if !ok {
+			return errors.New("Config Component Not Find: " + name)
+		}
+		if err = _SetFlagValue(ctx, fs, "component."+name, "", values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package gsf
+
+import (
+	"context"
+	"errors"
+	"github.com/kzangv/gsf-fof/logger"
+	"github.com/urfave/cli/v2"
+	"reflect"
+	"sort"
+)
+
+const (
+	_ServiceName = "service" // 主服务名称
+)
+
+type _Service struct {
+	name string
+	raw  interface{} // 原始服务，用于检测可选接口
+	ser  ContextService
+	log  logger.Interface
+}
+
+type _ServiceResult struct {
+	ser *_Service
+	err error
+}
+
+// loadService 合并主服务与命名服务，主服务在前，命名服务按名称排序
+func loadService(ser Service, ctxSer ContextService, sers map[string]Service, ctxSers map[string]ContextService) ([]*_Service, error) {
+	ret := make([]*_Service, 0, len(sers)+len(ctxSers)+1)
+	if ctxSer != nil {
+		ret = append(ret, &_Service{name: _ServiceName, raw: ctxSer, ser: ctxSer})
+	} else if ser != nil {
+		ret = append(ret, &_Service{name: _ServiceName, raw: ser, ser: WrapService{ser}})
+	}
+
+	names := make([]string, 0, len(sers)+len(ctxSers))
+	items := make(map[string]*_Service, len(sers)+len(ctxSers))
+	for k, v := range sers {
+		names, items[k] = append(names, k), &_Service{name: k, raw: v, ser: WrapService{v}}
+	}
+	for k, v := range ctxSers {
+		if _, ok := items[k]; ok {
+			return nil, errors.New("Service Name Repeat: " + k)
+		}
+		names, items[k] = append(names, k), &_Service{name: k, raw: v, ser: v}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == _ServiceName || name == "" {
+			return nil, errors.New("Service Name Is Invalid: " + name)
+		}
+		ret = append(ret, items[name])
+	}
+
+	if len(ret) == 0 {
+		return nil, errors.New("Service Not Find")
+	}
+	return ret, nil
+}
+
+// CliFlags 服务的命令行参数，命名服务的参数名以服务名为前缀，如 admin-web-port
+func (s *_Service) CliFlags() []cli.Flag {
+	fs := s.ser.CliFlags()
+	if s.name == _ServiceName {
+		return fs
+	}
+	for _, f := range fs {
+		v := reflect.ValueOf(f)
+		if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+			continue
+		}
+		if nv := v.Elem().FieldByName("Name"); nv.IsValid() && nv.CanSet() && nv.Kind() == reflect.String {
+			nv.SetString(s.name + "-" + nv.String())
+		}
+		if av := v.Elem().FieldByName("Aliases"); av.IsValid() && av.CanSet() && av.Type() == reflect.TypeOf([]string{}) {
+			aliases := make([]string, 0, av.Len())
+			for _, a := range av.Interface().([]string) {
+				aliases = append(aliases, s.name+"-"+a)
+			}
+			av.Set(reflect.ValueOf(aliases))
+		}
+	}
+	return fs
+}
+
+// runService 并发运行所有服务，任一服务出错或收到退出信号时返回，返回仍在运行的服务
+func (app *Application) runService(done chan _ServiceResult) (map[*_Service]bool, error) {
+	pending := make(map[*_Service]bool, len(app.sers))
+	for _, v := range app.sers {
+		pending[v] = true
+		go func(s *_Service) {
+			done <- _ServiceResult{ser: s, err: s.ser.Run(app.ctx, s.log, &app.Cfg)}
+		}(v)
+	}
+
+	for len(pending) > 0 {
+		select {
+		case r := <-done:
+			delete(pending, r.ser)
+			if r.err != nil {
+				if len(app.sers) > 1 {
+					r.err = errors.New("Service Run Error: " + r.ser.name + ": " + r.err.Error())
+				}
+				return pending, r.err
+			}
+		case <-app.ctx.Done():
+			return pending, nil
+		}
+	}
+	return pending, nil
+}
+
+// closeService 并发关闭仍在运行的服务，并等待服务运行结束
+func (app *Application) closeService(ctx context.Context, report *_CloseReport, pending map[*_Service]bool, done <-chan _ServiceResult) error {
+	var err error
+	for v := range pending {
+		go v.ser.Close(ctx)
+	}
+	for len(pending) > 0 {
+		select {
+		case r := <-done:
+			delete(pending, r.ser)
+			report.Add(r.ser.name, nil)
+			if err == nil && r.err != nil {
+				err = r.err
+			}
+		case <-ctx.Done():
+			for _, v := range app.sers {
+				if pending[v] {
+					report.Timeout(v.name)
+				}
+			}
+			return err
+		}
+	}
+	return err
+}
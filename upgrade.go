@@ -0,0 +1,267 @@
+package gsf
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// EnvInheritFds 平滑重启时传递给新进程的监听名称列表，第 i 个名称对应文件描述符 3+i
+	EnvInheritFds = "GSF_INHERIT_FDS"
+	// EnvUpgradeReadyFd 平滑重启时新进程通知旧进程启动完成的管道文件描述符
+	EnvUpgradeReadyFd = "GSF_UPGRADE_READY_FD"
+
+	// DefaultUpgradeTimeout 旧进程等待新进程启动完成的超时时间(秒)
+	DefaultUpgradeTimeout = 60
+
+	_InheritFdsStart   = 3
+	_InheritAdminName  = ":admin" // 管理服务的监听名称
+	_InheritNameSep    = ":"      // 服务名与服务内监听名的分隔符
+	_UpgradeReadyByte  = 'R'
+	_UpgradeReadyCheck = time.Millisecond * 20
+)
+
+// Upgrader 服务可选接口，支持平滑重启时在新旧进程间传递监听
+type Upgrader interface {
+	// ListenerFiles 旧进程调用，返回需要传递给新进程的监听文件，key 为服务内的监听名称，主监听为空
+	ListenerFiles() (map[string]*os.File, error)
+	// InheritListeners 新进程调用，使用旧进程传递的监听
+	InheritListeners(map[string]net.Listener)
+	// Addr 服务完成监听前返回 nil，新进程以此判断服务是否启动完成
+	Addr() net.Addr
+}
+
+// _UpgradeExec 平滑重启时新进程的启动命令，测试时替换
+var _UpgradeExec = func(args []string) (string, []string, error) {
+	exe, err := os.Executable()
+	return exe, args, err
+}
+
+// _InheritName 服务监听在进程间传递时的名称
+func _InheritName(service, name string) string {
+	if name == "" {
+		return service
+	}
+	return service + _InheritNameSep + name
+}
+
+// _InheritListeners 解析旧进程传递的监听
+func _InheritListeners() (map[string]net.Listener, error) {
+	names := os.Getenv(EnvInheritFds)
+	if names == "" {
+		return nil, nil
+	}
+	_ = os.Unsetenv(EnvInheritFds)
+
+	ret := make(map[string]net.Listener)
+	for i, name := range strings.Split(names, ",") {
+		f := os.NewFile(uintptr(_InheritFdsStart+i), name)
+		ln, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, errors.New("Inherit Listener Error: " + name + ": " + err.Error())
+		}
+		ret[name] = ln
+	}
+	return ret, nil
+}
+
+// inheritListener 新进程启动时将旧进程传递的监听交给对应的服务
+func (app *Application) inheritListener() error {
+	if fd, err := strconv.Atoi(os.Getenv(EnvUpgradeReadyFd)); err == nil {
+		_ = os.Unsetenv(EnvUpgradeReadyFd)
+		app.upgradeReady = os.NewFile(uintptr(fd), EnvUpgradeReadyFd)
+	}
+
+	lns, err := _InheritListeners()
+	if err != nil {
+		return err
+	}
+	if ln, ok := lns[_InheritAdminName]; ok && app.adminAddr != "" {
+		app.adminLn = ln
+		delete(lns, _InheritAdminName)
+	}
+	for _, v := range app.sers {
+		u, ok := v.raw.(Upgrader)
+		if !ok {
+			continue
+		}
+		sLns := make(map[string]net.Listener)
+		for name, ln := range lns {
+			switch {
+			case name == v.name:
+				sLns[""] = ln
+			case strings.HasPrefix(name, v.name+_InheritNameSep):
+				sLns[strings.TrimPrefix(name, v.name+_InheritNameSep)] = ln
+			default:
+				continue
+			}
+			delete(lns, name)
+		}
+		if len(sLns) > 0 {
+			u.InheritListeners(sLns)
+		}
+	}
+	for _, ln := range lns {
+		_ = ln.Close()
+	}
+	return nil
+}
+
+// notifyUpgrade 新进程所有服务完成监听后通知旧进程退出，启动失败时关闭管道
+func (app *Application) notifyUpgrade() {
+	f := app.upgradeReady
+	if f == nil {
+		return
+	}
+	app.upgradeReady = nil
+	defer f.Close()
+
+	ticker := time.NewTicker(_UpgradeReadyCheck)
+	defer ticker.Stop()
+	for {
+		ready := true
+		for _, v := range app.sers {
+			if u, ok := v.raw.(Upgrader); ok && u.Addr() == nil {
+				ready = false
+				break
+			}
+		}
+		if ready {
+			_, _ = f.Write([]byte{_UpgradeReadyByte})
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-app.ctx.Done():
+			return
+		}
+	}
+}
+
+// upgradeFiles 收集需要传递给新进程的监听文件，包括服务及管理服务的监听
+func (app *Application) upgradeFiles() ([]*os.File, []string, error) {
+	files, names := make([]*os.File, 0, len(app.sers)+1), make([]string, 0, len(app.sers)+1)
+	for _, v := range app.sers {
+		u, ok := v.raw.(Upgrader)
+		if !ok {
+			continue
+		}
+		fs, err := u.ListenerFiles()
+		if err != nil {
+			return files, names, errors.New(v.name + ": " + err.Error())
+		}
+		for name, f := range fs {
+			files, names = append(files, f), append(names, _InheritName(v.name, name))
+		}
+	}
+	if len(files) == 0 {
+		return files, names, nil
+	}
+	if ln, ok := app.adminLn.(*net.TCPListener); ok {
+		f, err := ln.File()
+		if err != nil {
+			return files, names, errors.New("admin: " + err.Error())
+		}
+		files, names = append(files, f), append(names, _InheritAdminName)
+	}
+	return files, names, nil
+}
+
+// upgrade 启动新进程并传递监听，新进程所有服务完成监听后当前进程优雅退出，新进程启动失败时当前进程继续运行
+func (app *Application) upgrade() {
+	if !atomic.CompareAndSwapInt32(&app.upgrading, 0, 1) {
+		app.Log.WarnForce("Application Upgrade Is Running\n")
+		return
+	}
+	started := false
+	defer func() {
+		if !started {
+			atomic.StoreInt32(&app.upgrading, 0)
+		}
+	}()
+
+	files, names, err := app.upgradeFiles()
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	if err != nil {
+		app.Log.ErrorForce("Application Upgrade Error: %s", err.Error())
+		return
+	}
+	if len(files) == 0 {
+		app.Log.WarnForce("Application Upgrade Not Support\n")
+		return
+	}
+
+	var args []string
+	if len(app.args) > 1 {
+		args = app.args[1:]
+	}
+	exe, args, err := _UpgradeExec(args)
+	if err != nil {
+		app.Log.ErrorForce("Application Upgrade Error: %s", err.Error())
+		return
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		app.Log.ErrorForce("Application Upgrade Error: %s", err.Error())
+		return
+	}
+	defer w.Close()
+
+	cmd := exec.Command(exe, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = append(files, w)
+	cmd.Env = make([]string, 0, len(os.Environ())+2)
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, EnvInheritFds+"=") && !strings.HasPrefix(e, EnvUpgradeReadyFd+"=") {
+			cmd.Env = append(cmd.Env, e)
+		}
+	}
+	cmd.Env = append(cmd.Env,
+		EnvInheritFds+"="+strings.Join(names, ","),
+		EnvUpgradeReadyFd+"="+strconv.Itoa(_InheritFdsStart+len(files)))
+	if err = cmd.Start(); err != nil {
+		_ = r.Close()
+		app.Log.ErrorForce("Application Upgrade Error: %s", err.Error())
+		return
+	}
+	started = true
+	for _, f := range files {
+		_RestoreNonblock(f)
+	}
+	app.Log.WarnForce("Application Upgrade: new process %d\n", cmd.Process.Pid)
+	go app.waitUpgrade(cmd, r)
+}
+
+// waitUpgrade 等待新进程启动完成，超时或新进程退出时终止新进程并继续运行
+func (app *Application) waitUpgrade(cmd *exec.Cmd, r *os.File) {
+	defer r.Close()
+	defer atomic.StoreInt32(&app.upgrading, 0)
+
+	_ = r.SetReadDeadline(time.Now().Add(DefaultUpgradeTimeout * time.Second))
+	buf := make([]byte, 1)
+	if _, err := io.ReadFull(r, buf); err != nil || buf[0] != _UpgradeReadyByte {
+		if err == nil {
+			err = errors.New("ready message is invalid")
+		}
+		app.Log.ErrorForce("Application Upgrade Error: new process %d is not ready: %s", cmd.Process.Pid, err.Error())
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return
+	}
+
+	app.Log.WarnForce("Application Upgrade: new process %d is ready\n", cmd.Process.Pid)
+	_ = cmd.Process.Release()
+	app.cancel()
+}
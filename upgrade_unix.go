@@ -0,0 +1,20 @@
+//go:build !windows
+
+package gsf
+
+import (
+	"os"
+	"syscall"
+)
+
+// _UpgradeSignal 触发平滑重启的信号
+var _UpgradeSignal os.Signal = syscall.SIGUSR2
+
+// _RestoreNonblock 启动子进程时 Fd() 会将文件设为阻塞模式，且与原监听共享该状态，需恢复为非阻塞，否则关闭监听时会阻塞在 accept 上
+func _RestoreNonblock(f *os.File) {
+	if rc, err := f.SyscallConn(); err == nil {
+		_ = rc.Control(func(fd uintptr) {
+			_ = syscall.SetNonblock(int(fd), true)
+		})
+	}
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package gsf
+
+import (
+	"os"
+)
+
+// _UpgradeSignal windows 不支持平滑重启
+var _UpgradeSignal os.Signal = nil
+
+// _RestoreNonblock windows 不支持平滑重启
+func _RestoreNonblock(f *os.File) {}
@@ -1,45 +1,119 @@
 package gsf
 
 import (
-	"fmt"
+	"context"
 	"github.com/kzangv/gsf-fof/logger"
+	"github.com/kzangv/gsf-fof/web/middleware"
 	"github.com/urfave/cli/v2"
+	"net"
 	"net/http"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const (
-	CliWebIP           = "web-ip"
-	CliWebPort         = "web-port"
-	CliWebReadTimeout  = "web-r-timeout"
-	CliWebWriteTimeout = "web-w-timeout"
-	CliWebIdleTimeout  = "web-idle-timeout"
+	CliWebIP              = "web-ip"
+	CliWebPort            = "web-port"
+	CliWebReadTimeout     = "web-r-timeout"
+	CliWebWriteTimeout    = "web-w-timeout"
+	CliWebIdleTimeout     = "web-idle-timeout"
+	CliWebShutdownTimeout = "web-shutdown-timeout"
+	CliWebShutdownDelay   = "web-shutdown-delay"
+	CliWebHealth          = "web-health"
+	CliWebHealthAddr      = "web-health-addr"
+	CliWebTLSCert         = "web-tls-cert"
+	CliWebTLSKey          = "web-tls-key"
+	CliWebTLSMinVersion   = "web-tls-min-version"
+	CliWebTLSClientCA     = "web-tls-client-ca"
+	CliWebTLSClientAuth   = "web-tls-client-auth"
+	CliWebTLSReload       = "web-tls-reload"
+	CliWebTLSDisableHTTP2 = "web-tls-disable-http2"
+	CliWebUnix            = "web-unix"
+	CliWebSystemd         = "web-systemd"
+
+	DefaultWebShutdownTimeout = 30
 )
 
 type WebConfig struct {
-	IP      string `json:"ip"   yaml:"ip"`
-	Port    int    `json:"port" yaml:"port"`
+	IP      string `json:"ip"      yaml:"ip"`
+	Port    int    `json:"port"    yaml:"port"`
+	Unix    string `json:"unix"    yaml:"unix"`
+	Systemd bool   `json:"systemd" yaml:"systemd"`
 	Timeout struct {
-		Read  int `json:"read"  yaml:"read"`
-		Write int `json:"write" yaml:"write"`
-		Idle  int `json:"idle"  yaml:"idle"`
+		Read     int `json:"read"     yaml:"read"`
+		Write    int `json:"write"    yaml:"write"`
+		Idle     int `json:"idle"     yaml:"idle"`
+		Shutdown int `json:"shutdown" yaml:"shutdown"`
+		Delay    int `json:"delay"    yaml:"delay"` // 关闭前延迟，期间就绪检查失败但继续处理请求
 	} `json:"timeout" yaml:"timeout"`
+	Health struct {
+		Enable bool   `json:"enable" yaml:"enable"`
+		Addr   string `json:"addr"   yaml:"addr"`
+	} `json:"health" yaml:"health"`
+	TLS struct {
+		Cert         string `json:"cert"          yaml:"cert"`
+		Key          string `json:"key"           yaml:"key"`
+		MinVersion   string `json:"min_version"   yaml:"min_version"`
+		ClientCA     string `json:"client_ca"     yaml:"client_ca"`
+		ClientAuth   string `json:"client_auth"   yaml:"client_auth"`
+		Reload       int    `json:"reload"        yaml:"reload"`
+		DisableHTTP2 bool   `json:"disable_http2" yaml:"disable_http2"`
+	} `json:"tls" yaml:"tls"`
 }
 
 type WebService struct {
 	Cfg                   WebConfig
 	Handler               http.Handler
+	Listener              net.Listener // 指定监听，优先于 Cfg 中的监听配置
 	BeforeRun, BeforeInit func(l logger.Interface) error
+
+	lock      sync.Mutex
+	log       logger.Interface
+	srv       *http.Server
+	ln        net.Listener
+	healthSrv *http.Server
+	healthLn  net.Listener
+	healthInh net.Listener // 旧进程传递的健康检查监听
+	done      chan struct{}
+	closed    bool
+	closing   int32
+	ready     func() error
+	cert      *_CertLoader
+	stop      chan struct{}
+	addr      atomic.Value
+	mws       []middleware.Middleware
+}
+
+// Use 添加中间件，在 Run 时按添加顺序由外向内包装 Handler
+func (c *WebService) Use(mws ...middleware.Middleware) *WebService {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.mws = append(c.mws, mws...)
+	return c
 }
 
 func (c *WebService) CliFlags() []cli.Flag {
 	return []cli.Flag{
 		&cli.StringFlag{Name: CliWebIP, Value: "0.0.0.0", Usage: "web service ip", Action: func(_ *cli.Context, i string) error { c.Cfg.IP = i; return nil }},
 		&cli.IntFlag{Name: CliWebPort, Value: 9980, Usage: "web service port", Action: func(_ *cli.Context, i int) error { c.Cfg.Port = i; return nil }},
+		&cli.StringFlag{Name: CliWebUnix, Value: "", Usage: "web service unix socket path, instead of ip and port", Action: func(_ *cli.Context, i string) error { c.Cfg.Unix = i; return nil }},
+		&cli.BoolFlag{Name: CliWebSystemd, Value: false, Usage: "web service use systemd socket activation listener(LISTEN_FDS)", Action: func(_ *cli.Context, i bool) error { c.Cfg.Systemd = i; return nil }},
 		&cli.IntFlag{Name: CliWebReadTimeout, Value: 0, Usage: "web service read timeout", Action: func(_ *cli.Context, i int) error { c.Cfg.Timeout.Read = i; return nil }},
 		&cli.IntFlag{Name: CliWebWriteTimeout, Value: 0, Usage: "web service write timeout", Action: func(_ *cli.Context, i int) error { c.Cfg.Timeout.Write = i; return nil }},
 		&cli.IntFlag{Name: CliWebIdleTimeout, Value: 0, Usage: "web service idle timeout", Action: func(_ *cli.Context, i int) error { c.Cfg.Timeout.Idle = i; return nil }},
+		&cli.IntFlag{Name: CliWebShutdownTimeout, Value: DefaultWebShutdownTimeout, Usage: "web service shutdown timeout", Action: func(_ *cli.Context, i int) error { c.Cfg.Timeout.Shutdown = i; return nil }},
+		&cli.IntFlag{Name: CliWebShutdownDelay, Value: 0, Usage: "web service shutdown delay, readiness check fails while still serving during the delay", Action: func(_ *cli.Context, i int) error { c.Cfg.Timeout.Delay = i; return nil }},
+		&cli.BoolFlag{Name: CliWebHealth, Value: false, Usage: "web service health check(" + WebHealthPath + ", " + WebReadyPath + ")", Action: func(_ *cli.Context, i bool) error { c.Cfg.Health.Enable = i; return nil }},
+		&cli.StringFlag{Name: CliWebHealthAddr, Value: "", Usage: "web service health check addr, empty is same as web service", Action: func(_ *cli.Context, i string) error { c.Cfg.Health.Addr = i; return nil }},
+		&cli.StringFlag{Name: CliWebTLSCert, Value: "", Usage: "web service tls cert file", Action: func(_ *cli.Context, i string) error { c.Cfg.TLS.Cert = i; return nil }},
+		&cli.StringFlag{Name: CliWebTLSKey, Value: "", Usage: "web service tls key file", Action: func(_ *cli.Context, i string) error { c.Cfg.TLS.Key = i; return nil }},
+		&cli.StringFlag{Name: CliWebTLSMinVersion, Value: "1.2", Usage: "web service tls min version(1.0, 1.1, 1.2, 1.3)", Action: func(_ *cli.Context, i string) error { c.Cfg.TLS.MinVersion = i; return nil }},
+		&cli.StringFlag{Name: CliWebTLSClientCA, Value: "", Usage: "web service tls client ca file, enable mTLS", Action: func(_ *cli.Context, i string) error { c.Cfg.TLS.ClientCA = i; return nil }},
+		&cli.StringFlag{Name: CliWebTLSClientAuth, Value: WebTLSClientAuthRequire, Usage: "web service tls client auth(" + WebTLSClientAuthRequire + ", " + WebTLSClientAuthVerify + ")", Action: func(_ *cli.Context, i string) error { c.Cfg.TLS.ClientAuth = i; return nil }},
+		&cli.IntFlag{Name: CliWebTLSReload, Value: 0, Usage: "web service tls cert file check interval, 0 is only reload on SIGHUP", Action: func(_ *cli.Context, i int) error { c.Cfg.TLS.Reload = i; return nil }},
+		&cli.BoolFlag{Name: CliWebTLSDisableHTTP2, Value: false, Usage: "web service disable http2 when tls is enabled", Action: func(_ *cli.Context, i bool) error { c.Cfg.TLS.DisableHTTP2 = i; return nil }},
 	}
 }
 
@@ -66,23 +140,128 @@ func (c *WebService) Run(l logger.Interface, cfg *Config) error {
 		}
 	}
 
-	if c.Cfg.IP == "" {
-		l.WarnForce("Listening Server [[[ Run-Mode: %s ]]] http://127.0.0.1:%d\n", cfg.EnvDesc(), c.Cfg.Port)
-	} else {
-		l.WarnForce("Listening Server [[[ Run-Mode: %s ]]] http://%s:%d\n", cfg.EnvDesc(), c.Cfg.IP, c.Cfg.Port)
-	}
-
 	// web
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return nil
+	}
+	ln, err := c.listen()
+	if err != nil {
+		c.lock.Unlock()
+		return err
+	}
+	handler := c.Handler
+	if len(c.mws) > 0 {
+		handler = middleware.Chain(handler, c.mws...)
+	}
 	srv := &http.Server{
-		Addr:         fmt.Sprintf("%s:%d", c.Cfg.IP, c.Cfg.Port),
 		ReadTimeout:  time.Duration(c.Cfg.Timeout.Read) * time.Second,
 		WriteTimeout: time.Duration(c.Cfg.Timeout.Write) * time.Second,
 		IdleTimeout:  time.Duration(c.Cfg.Timeout.Idle) * time.Second,
-		Handler:      c.Handler,
+		Handler:      handler,
 	}
 	srv.SetKeepAlivesEnabled(true)
-	return srv.ListenAndServe()
+	isTLS, err := c.setupTLS(l, srv)
+	if err != nil {
+		c.lock.Unlock()
+		_ = ln.Close()
+		return err
+	}
+	if c.Cfg.Health.Enable {
+		if c.Cfg.Health.Addr == "" {
+			srv.Handler = c.healthHandler(handler)
+		} else {
+			hln, hErr := c.listenHealth()
+			if hErr != nil {
+				c.release()
+				c.lock.Unlock()
+				_ = ln.Close()
+				return hErr
+			}
+			c.healthSrv = &http.Server{Handler: c.healthHandler(http.NotFoundHandler())}
+			go c.runHealth(l, c.healthSrv, hln)
+		}
+	}
+	c.log, c.srv, c.done = l, srv, make(chan struct{})
+	// 所有监听完成后才对外提供地址
+	c.addr.Store(ln.Addr())
+	c.lock.Unlock()
+
+	scheme := "http"
+	if isTLS {
+		scheme = "https"
+	}
+	if ln.Addr().Network() == "unix" {
+		scheme = "unix"
+	}
+	l.WarnForce("Listening Server [[[ Run-Mode: %s ]]] %s://%s\n", cfg.EnvDesc(), scheme, ln.Addr().String())
+
+	if isTLS {
+		// 证书由 TLSConfig.GetCertificate 提供
+		err = srv.ServeTLS(ln, "", "")
+	} else {
+		err = srv.Serve(ln)
+	}
+	if err != http.ErrServerClosed {
+		c.lock.Lock()
+		c.release()
+		c.lock.Unlock()
+		return err
+	}
+	// 等待 Close 将正在处理的请求处理完成
+	<-c.done
+	return nil
+}
+
+// release 关闭健康检查服务及证书检查，需持有 c.lock
+func (c *WebService) release() {
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+	if c.healthSrv != nil {
+		_ = c.healthSrv.Close()
+		c.healthSrv = nil
+	}
 }
 
 func (c *WebService) Close() {
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return
+	}
+	c.closed = true
+	atomic.StoreInt32(&c.closing, 1)
+	srv := c.srv
+	c.lock.Unlock()
+	if srv == nil {
+		return
+	}
+
+	// 延迟期间就绪检查失败，等待负载均衡摘除后再停止接收请求
+	if c.Cfg.Timeout.Delay > 0 {
+		c.log.WarnForce("Web Server Shutdown Delay(%ds)\n", c.Cfg.Timeout.Delay)
+		time.Sleep(time.Duration(c.Cfg.Timeout.Delay) * time.Second)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	defer close(c.done)
+	// 健康检查在请求处理完成后关闭
+	defer c.release()
+
+	timeout := c.Cfg.Timeout.Shutdown
+	if timeout <= 0 {
+		timeout = DefaultWebShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	// 停止接收新连接，并等待正在处理的请求结束
+	if err := c.srv.Shutdown(ctx); err != nil {
+		c.log.WarnForce("Web Server Shutdown Timeout(%ds): %s\n", timeout, err.Error())
+		_ = c.srv.Close()
+	}
 }
@@ -0,0 +1,80 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"github.com/kzangv/gsf-fof/web/request"
+	"github.com/kzangv/gsf-fof/web/response"
+	"net/http"
+	"reflect"
+)
+
+var (
+	ErrCodeBind     = http.StatusBadRequest          // 请求参数绑定或校验失败
+	ErrCodeInternal = http.StatusInternalServerError // 处理函数返回非 response.Error 错误
+)
+
+// _HasTag 结构体（含嵌入及嵌套结构体）中是否存在指定 tag 的字段
+func _HasTag(t reflect.Type, tag string) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if v, ok := f.Tag.Lookup(tag); ok && v != "-" {
+			return true
+		}
+		if (f.PkgPath == "" || f.Anonymous) && _HasTag(f.Type, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorResponse 错误转换为响应：response.Error 的 Code 在 CodeMsgMap 中时使用对应提示，否则使用错误内容
+func ErrorResponse(err error) response.Response {
+	var e response.Error
+	if errors.As(err, &e) {
+		if _, ok := response.CodeMsgMap[e.Code()]; ok {
+			ret := response.New().SetErrCode(e.Code())
+			if v, ok := e.(response.StatusError); ok {
+				ret = response.WithHTTPStatus(ret, v.HTTPStatus())
+			}
+			return ret
+		}
+		return response.New().SetCustomError(e)
+	}
+	return response.WithHTTPStatus(response.New().SetErrCode(ErrCodeInternal).AddError(err), http.StatusInternalServerError)
+}
+
+// Handle 将 func(ctx, *Req) (*Resp, error) 转换为 http.Handler：
+// 按请求方法、Content-Type 及 header、uri 标签依次绑定参数并校验，后绑定的优先，返回值渲染为 response.Response
+func Handle[Req, Resp any](fn func(context.Context, *Req) (*Resp, error)) http.Handler {
+	t := reflect.TypeOf((*Req)(nil)).Elem()
+	var post []request.Binder
+	if _HasTag(t, "header") {
+		post = append(post, request.HeaderBind{})
+	}
+	if _HasTag(t, "uri") {
+		post = append(post, request.PathBind{})
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := new(Req)
+		binders := append([]request.Binder{request.Default(r.Method, r.Header.Get("Content-Type"))}, post...)
+		if err := request.BindWith(r, req, binders...); err != nil {
+			response.Render(w, r, response.WithHTTPStatus(response.New().SetErrCode(ErrCodeBind).AddError(err), http.StatusBadRequest))
+			return
+		}
+
+		data, err := fn(r.Context(), req)
+		if err != nil {
+			response.Render(w, r, ErrorResponse(err))
+			return
+		}
+		response.Render(w, r, response.New().SetData(data))
+	})
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/kzangv/gsf-fof/logger"
+	"net/http"
+	"time"
+)
+
+// AccessLog 记录访问日志，使用 InfoForce 输出，不受日志级别限制
+func AccessLog(l logger.Interface) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			begin, rw := time.Now(), &_ResponseWriter{ResponseWriter: w}
+			defer func() {
+				l.InfoForce("%s %s %s %d %dB %s [rid:%s]\n", r.RemoteAddr, r.Method, r.URL.RequestURI(),
+					rw.Status(), rw.size, time.Since(begin).String(), GetRequestID(r.Context()))
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
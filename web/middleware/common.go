@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"bufio"
+	"github.com/kzangv/gsf-fof/web/response"
+	"net"
+	"net/http"
+)
+
+// Middleware http 中间件
+type Middleware func(http.Handler) http.Handler
+
+// Chain 组合中间件，第一个中间件位于最外层
+func Chain(h http.Handler, mws ...Middleware) http.Handler {
+	if h == nil {
+		h = http.DefaultServeMux
+	}
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// WriteErrCode 按 Accept 输出 response.New().SetErrCode(code)
+func WriteErrCode(w http.ResponseWriter, r *http.Request, status, code int) {
+	response.RenderStatus(w, r, status, response.New().SetErrCode(code))
+}
+
+// _ResponseWriter 记录响应状态码及长度
+type _ResponseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (w *_ResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *_ResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *_ResponseWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *_ResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack 支持 WebSocket 等协议升级，连接交由调用方处理
+func (w *_ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+func (w *_ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"github.com/kzangv/gsf-fof/web/response"
+	"net/http"
+	"time"
+)
+
+type _TimeoutWriter struct {
+	http.ResponseWriter
+	contentType string
+}
+
+// WriteHeader 超时响应没有 Content-Type 时按协商的格式设置
+func (w _TimeoutWriter) WriteHeader(code int) {
+	if code == http.StatusServiceUnavailable && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", w.contentType)
+		w.Header().Add("Vary", "Accept")
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Timeout 请求处理超时后按 Accept 返回 code 对应的错误响应，请求 context 同时设置超时
+func Timeout(d time.Duration, code int) Middleware {
+	ret := response.New().SetErrCode(code)
+	return func(next http.Handler) http.Handler {
+		// http.TimeoutHandler 的响应内容固定，按格式分别创建
+		hs := make(map[string]http.Handler, 3)
+		for _, format := range []string{response.MIMEJson, response.MIMEXml, response.MIMEMsgPack} {
+			if body, err := response.Marshal(format, ret); err == nil {
+				hs[format] = http.TimeoutHandler(next, d, string(body))
+			}
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			format := response.Negotiate(r, ret)
+			h, ok := hs[format]
+			if !ok {
+				format, h = response.MIMEJson, hs[response.MIMEJson]
+			}
+			h.ServeHTTP(_TimeoutWriter{ResponseWriter: w, contentType: response.ContentType(format)}, r)
+		})
+	}
+}
+
+// BodyLimit 限制请求体大小，超出时返回 code 对应的错误响应
+func BodyLimit(n int64, code int) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				WriteErrCode(w, r, http.StatusRequestEntityTooLarge, code)
+				return
+			}
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, n)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"github.com/kzangv/gsf-fof/logger"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recovery 捕获 panic，记录日志并返回 code 对应的错误响应
+func Recovery(l logger.Interface, code int) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if err := recover(); err != nil {
+					if err == http.ErrAbortHandler {
+						panic(err)
+					}
+					l.ErrorForce("Panic Recovery: %s %s [rid:%s]: %v\n%s", r.Method, r.URL.RequestURI(), GetRequestID(r.Context()), err, debug.Stack())
+					WriteErrCode(w, r, http.StatusInternalServerError, code)
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
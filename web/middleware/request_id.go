@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const (
+	HeaderRequestID = "X-Request-ID"
+
+	_RequestIDMaxLen = 128
+)
+
+type _RequestIDKey struct{}
+
+// GetRequestID 获取请求 ID
+func GetRequestID(ctx context.Context) string {
+	if v, ok := ctx.Value(_RequestIDKey{}).(string); ok {
+		return v
+	}
+	return ""
+}
+
+// NewRequestID 生成 32 位十六进制请求 ID
+func NewRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestID 沿用请求头中的 X-Request-ID，不存在时生成，并写入响应头及请求 context
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rid := r.Header.Get(HeaderRequestID)
+			if rid == "" || len(rid) > _RequestIDMaxLen {
+				rid = NewRequestID()
+				r.Header.Set(HeaderRequestID, rid)
+			}
+			w.Header().Set(HeaderRequestID, rid)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), _RequestIDKey{}, rid)))
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package request
+
+import (
+	"io"
+	"mime"
+	"net/http"
+)
+
+const queryTagName = "query"
+
+// MultiBind 从多个来源绑定，按以下顺序绑定，后绑定的来源优先，全部完成后校验一次：
+// json 请求体(json)、请求体及 query(form)、query(query)、header(header)、路径参数(uri)；
+// json 请求体按 encoding/json 规则绑定，未设置 json 标签的字段按字段名匹配，其他来源只绑定设置了对应来源标签的字段
+type MultiBind struct {
+	Validator Validator // 为空时使用 Validate
+	Multipart MultipartOption
+}
+
+// Bind 使用 MultiBind 绑定
+func Bind(req *http.Request, obj interface{}) error {
+	return MultiBind{}.Bind(req, obj)
+}
+
+func (b MultiBind) Bind(req *http.Request, obj interface{}) error {
+	files, err := b.bindUploads(req, obj)
+	if err == nil {
+		err = _Validate(b.Validator, obj)
+	}
+	if err != nil {
+		_RemoveUploads(files)
+	}
+	return err
+}
+
+func (b MultiBind) validator() Validator {
+	return b.Validator
+}
+
+func (b MultiBind) bind(req *http.Request, obj interface{}) error {
+	files, err := b.bindUploads(req, obj)
+	if err != nil {
+		_RemoveUploads(files)
+	}
+	return err
+}
+
+func (b MultiBind) bindUploads(req *http.Request, obj interface{}) (map[string][]*UploadFile, error) {
+	errs := &BindError{}
+	add := func(err error) bool {
+		return err == nil || errs.add("", err)
+	}
+
+	ct, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if ct == MIMEJson && req.Body != nil && req.Body != http.NoBody {
+		if err := _JsonDecode(req.Body, obj); err != io.EOF && !add(_BindError(SourceJson, err)) {
+			return nil, err
+		}
+	}
+
+	src := _UploadSource{}
+	if ct == MIMEMultipartForm {
+		files, err := b.Multipart.parse(req)
+		src.files = files
+		if err != nil && !add(_BindError(SourceForm, err)) {
+			return files, err
+		}
+	} else if err := req.ParseForm(); err != nil {
+		return nil, err
+	}
+	src._FormSource = _FormSource(req.Form)
+
+	for _, v := range []struct {
+		src         _Source
+		tag, source string
+	}{
+		{src, formTagName, SourceForm},
+		{_FormSource(req.URL.Query()), queryTagName, SourceQuery},
+		{_HeaderSource(req.Header), "header", SourceHeader},
+		{_PathSource(GetPathParams(req)), uriTagName, SourceUri},
+	} {
+		if err := _BindError(v.source, _PlanBind(obj, v.src, v.tag, true)); !add(err) {
+			return src.files, err
+		}
+	}
+	if len(errs.Fields) > 0 {
+		return src.files, errs
+	}
+	return src.files, nil
+}
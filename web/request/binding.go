@@ -0,0 +1,117 @@
+package request
+
+import (
+	"mime"
+	"net/http"
+)
+
+const (
+	MIMEJson          = "application/json"
+	MIMEForm          = "application/x-www-form-urlencoded"
+	MIMEMultipartForm = "multipart/form-data"
+	MIMEXml           = "application/xml"
+	MIMEXml2          = "text/xml"
+	MIMEYaml          = "application/yaml"
+	MIMEYaml2         = "application/x-yaml"
+	MIMEYaml3         = "text/yaml"
+	MIMEMsgPack       = "application/msgpack"
+	MIMEMsgPack2      = "application/x-msgpack"
+	MIMEProtobuf      = "application/x-protobuf"
+	MIMEProtoBuf      = "application/protobuf"
+)
+
+// _ContentBinders Content-Type 对应的绑定
+var _ContentBinders = map[string]Binder{
+	MIMEJson:          JsonBind{},
+	MIMEForm:          FormBind{},
+	MIMEMultipartForm: FormMultipartBind{},
+	MIMEXml:           XMLBind{},
+	MIMEXml2:          XMLBind{},
+	MIMEYaml:          YAMLBind{},
+	MIMEYaml2:         YAMLBind{},
+	MIMEYaml3:         YAMLBind{},
+	MIMEMsgPack:       MsgPackBind{},
+	MIMEMsgPack2:      MsgPackBind{},
+	MIMEProtobuf:      ProtoBind{},
+	MIMEProtoBuf:      ProtoBind{},
+}
+
+// RegisterBinder 注册 Content-Type 对应的绑定，同名会被覆盖，需在服务启动前注册
+func RegisterBinder(contentType string, b Binder) {
+	_ContentBinders[contentType] = b
+}
+
+// BinderOf 获取 Content-Type 对应的绑定，忽略 charset 等参数
+func BinderOf(contentType string) (Binder, bool) {
+	ct, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	b, ok := _ContentBinders[ct]
+	return b, ok
+}
+
+// Binder 请求绑定，Bind 完成绑定后执行校验
+type Binder interface {
+	Bind(req *http.Request, obj interface{}) error
+}
+
+// _Binder 只绑定不校验，用于多个绑定组合后统一校验
+type _Binder interface {
+	bind(req *http.Request, obj interface{}) error
+	validator() Validator
+}
+
+func _Validate(v Validator, obj interface{}) error {
+	if v != nil {
+		return v.Validate(obj)
+	}
+	return Validate(obj)
+}
+
+// Default 根据请求方法及 Content-Type 选择绑定：GET、HEAD、DELETE 及未知 Content-Type 使用 QueryBind
+func Default(method, contentType string) Binder {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		return QueryBind{}
+	}
+	if b, ok := BinderOf(contentType); ok {
+		return b
+	}
+	return QueryBind{}
+}
+
+// BindWith 依次执行多个绑定，全部完成后校验一次，使用第一个指定的 Validator；失败时删除已绑定上传文件的临时文件
+func BindWith(req *http.Request, obj interface{}, binders ...Binder) (err error) {
+	var (
+		validator Validator
+		uploads   []map[string][]*UploadFile
+	)
+	defer func() {
+		if err != nil {
+			for _, files := range uploads {
+				_RemoveUploads(files)
+			}
+		}
+	}()
+	for _, b := range binders {
+		if v, ok := b.(_Binder); ok {
+			if u, ok := b.(_UploadBinder); ok {
+				var files map[string][]*UploadFile
+				files, err = u.bindUploads(req, obj)
+				uploads = append(uploads, files)
+			} else {
+				err = v.bind(req, obj)
+			}
+			if validator == nil {
+				validator = v.validator()
+			}
+		} else {
+			err = b.Bind(req, obj)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return _Validate(validator, obj)
+}
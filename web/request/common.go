@@ -19,7 +19,8 @@ type SliceUnmarshaler interface {
 }
 
 var (
-	Validate = func(obj interface{}) error { return nil }
+	// Validate 绑定未指定 Validator 时使用的校验，默认只包含内置规则，自定义规则通过绑定的 Validator 指定
+	Validate = func(obj interface{}) error { return _DefaultValidator.Validate(obj) }
 
 	JsonEnableDecoderUseNumber             = false
 	JsonEnableDecoderDisallowUnknownFields = false
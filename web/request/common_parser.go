@@ -2,7 +2,6 @@ package request
 
 import (
 	"encoding/json"
-	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
@@ -18,10 +17,6 @@ type _SetOptions struct {
 	defaultValue    string
 }
 
-type _Setter interface {
-	TrySet(value reflect.Value, field reflect.StructField, key string, opt _SetOptions) (isSetted bool, err error)
-}
-
 func head(str, sep string) (head string, tail string) {
 	idx := strings.Index(str, sep)
 	if idx < 0 {
@@ -30,126 +25,6 @@ func head(str, sep string) (head string, tail string) {
 	return str[:idx], str[idx+len(sep):]
 }
 
-func _ValueSet(value reflect.Value, field reflect.StructField, form map[string][]string, tagValue string, opt _SetOptions) (isSetted bool, err error) {
-	vs, ok := form[tagValue]
-	if !ok && !opt.isDefaultExists {
-		return false, nil
-	}
-
-	switch value.Kind() {
-	case reflect.Slice:
-		if !ok {
-			vs = []string{opt.defaultValue}
-		}
-		if ok, err = _SliceUnmarshaler(value, vs); ok {
-			return true, err
-		}
-		return true, _SetSlice(vs, value, field)
-	case reflect.Array:
-		if !ok {
-			vs = []string{opt.defaultValue}
-		}
-		if ok, err = _SliceUnmarshaler(value, vs); ok {
-			return true, err
-		}
-		if len(vs) != value.Len() {
-			return false, fmt.Errorf("%q is not valid value for %s", vs, value.Type().String())
-		}
-		return true, _SetArray(vs, value, field)
-	default:
-		var val string
-		if !ok {
-			val = opt.defaultValue
-		}
-
-		if len(vs) > 0 {
-			val = vs[0]
-		}
-		return true, _SetValue(val, value, field)
-	}
-}
-
-func _TryToSetValue(value reflect.Value, field reflect.StructField, setter _Setter, tag string) (bool, error) {
-	var tagValue string
-	var setOpt _SetOptions
-
-	tagValue = field.Tag.Get(tag)
-	tagValue, opts := head(tagValue, ",")
-
-	if tagValue == "" { // default value is FieldName
-		tagValue = field.Name
-	}
-	if tagValue == "" { // when field is "_EmptyField" variable
-		return false, nil
-	}
-
-	var opt string
-	for len(opts) > 0 {
-		opt, opts = head(opts, ",")
-
-		if k, v := head(opt, "="); k == "default" {
-			setOpt.isDefaultExists = true
-			setOpt.defaultValue = v
-		}
-	}
-
-	return setter.TrySet(value, field, tagValue, setOpt)
-}
-
-func _Mapping(value reflect.Value, field reflect.StructField, setter _Setter, tag string) (bool, error) {
-	if field.Tag.Get(tag) == "-" { // just ignoring this field
-		return false, nil
-	}
-
-	var vKind = value.Kind()
-
-	if vKind == reflect.Ptr {
-		var isNew bool
-		vPtr := value
-		if value.IsNil() {
-			isNew = true
-			vPtr = reflect.New(value.Type().Elem())
-		}
-		isSetted, err := _Mapping(vPtr.Elem(), field, setter, tag)
-		if err != nil {
-			return false, err
-		}
-		if isNew && isSetted {
-			value.Set(vPtr)
-		}
-		return isSetted, nil
-	}
-
-	if vKind != reflect.Struct || !field.Anonymous {
-		ok, err := _TryToSetValue(value, field, setter, tag)
-		if err != nil {
-			return false, err
-		}
-		if ok {
-			return true, nil
-		}
-	}
-
-	if vKind == reflect.Struct {
-		tValue := value.Type()
-
-		var isSetted bool
-		for i := 0; i < value.NumField(); i++ {
-			sf := tValue.Field(i)
-			if sf.PkgPath != "" && !sf.Anonymous { // unexported
-				continue
-			}
-			ok, err := _Mapping(value.Field(i), tValue.Field(i), setter, tag)
-			if err != nil {
-				return false, err
-			}
-			isSetted = isSetted || ok
-		}
-		return isSetted, nil
-	}
-	return false, nil
-}
-
 func _SetIntField(val string, bitSize int, value reflect.Value) error {
 	if val == "" {
 		val = "0"
@@ -254,26 +129,6 @@ func _SetTimeDuration(val string, value reflect.Value) error {
 	return nil
 }
 
-func _SetArray(vals []string, value reflect.Value, field reflect.StructField) error {
-	for i, s := range vals {
-		err := _SetValue(s, value.Index(i), field)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func _SetSlice(vals []string, value reflect.Value, field reflect.StructField) error {
-	slice := reflect.MakeSlice(value.Type(), len(vals), len(vals))
-	err := _SetArray(vals, slice, field)
-	if err != nil {
-		return err
-	}
-	value.Set(slice)
-	return nil
-}
-
 func _SliceUnmarshaler(v reflect.Value, vs []string) (bool, error) {
 	if v.Kind() != reflect.Pointer && v.Type().Name() != "" && v.CanAddr() {
 		v = v.Addr()
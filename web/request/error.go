@@ -0,0 +1,107 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	SourceForm    = "form"
+	SourceQuery   = "query"
+	SourceHeader  = "header"
+	SourceJson    = "json"
+	SourceUri     = "uri"
+	SourceXml     = "xml"
+	SourceYaml    = "yaml"
+	SourceMsgPack = "msgpack"
+	SourceProto   = "protobuf"
+)
+
+// FieldError 单个字段的绑定错误
+type FieldError struct {
+	Path   string `json:"path"`   // 结构体字段路径，如 Page.Size
+	Field  string `json:"field"`  // 请求中的参数名
+	Source string `json:"source"` // 参数来源：form, query, header, json, uri, xml, yaml, msgpack, protobuf
+	Value  string `json:"value"`  // 原始值
+	Reason string `json:"reason"`
+}
+
+func (e *FieldError) Error() string {
+	return e.Source + " " + e.Field + " " + strconv.Quote(e.Value) + ": " + e.Reason
+}
+
+// BindError 绑定错误，包含所有绑定失败的字段
+type BindError struct {
+	Fields []*FieldError `json:"fields"`
+}
+
+func (e *BindError) Error() string {
+	items := make([]string, 0, len(e.Fields))
+	for _, v := range e.Fields {
+		items = append(items, v.Error())
+	}
+	return strings.Join(items, "; ")
+}
+
+// Errors 逐个字段的错误，用于 response 按字段输出
+func (e *BindError) Errors() []error {
+	ret := make([]error, 0, len(e.Fields))
+	for _, v := range e.Fields {
+		ret = append(ret, v)
+	}
+	return ret
+}
+
+// add 将子字段的绑定错误加入，并在路径前补充字段名；非绑定错误返回 false
+func (e *BindError) add(name string, err error) bool {
+	var fs []*FieldError
+	switch v := err.(type) {
+	case *FieldError:
+		fs = []*FieldError{v}
+	case *BindError:
+		fs = v.Fields
+	default:
+		return false
+	}
+	for _, f := range fs {
+		if f.Path == "" {
+			f.Path = name
+		} else if name != "" {
+			f.Path = name + "." + f.Path
+		}
+	}
+	e.Fields = append(e.Fields, fs...)
+	return true
+}
+
+func _FieldReason(err error) string {
+	var ne *strconv.NumError
+	if errors.As(err, &ne) {
+		return ne.Err.Error()
+	}
+	return err.Error()
+}
+
+// _BindError 绑定错误统一转换为 BindError，并设置参数来源
+func _BindError(source string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var ute *json.UnmarshalTypeError
+	if errors.As(err, &ute) {
+		err = &FieldError{Path: ute.Field, Field: ute.Field, Value: ute.Value, Reason: "cannot unmarshal into " + ute.Type.String()}
+	}
+
+	ret := &BindError{}
+	if !ret.add("", err) {
+		return err
+	}
+	for _, v := range ret.Fields {
+		if v.Source == "" {
+			v.Source = source
+		}
+	}
+	return ret
+}
@@ -7,26 +7,33 @@ import (
 
 type _FormSource map[string][]string
 
-func (form _FormSource) TrySet(value reflect.Value, field reflect.StructField, tagValue string, opt _SetOptions) (isSetted bool, err error) {
-	return _ValueSet(value, field, form, tagValue, opt)
+type FormBind struct {
+	Validator Validator // 为空时使用 Validate
+	Multipart MultipartOption
 }
 
-type FormBind struct {
+func (b FormBind) Bind(req *http.Request, obj interface{}) error {
+	if err := b.bind(req, obj); err != nil {
+		return err
+	}
+	return _Validate(b.Validator, obj)
 }
 
-func (FormBind) Bind(req *http.Request, obj interface{}) error {
+func (b FormBind) validator() Validator {
+	return b.Validator
+}
+
+func (b FormBind) bind(req *http.Request, obj interface{}) error {
 	if err := req.ParseForm(); err != nil {
 		return err
 	}
-	if err := req.ParseMultipartForm(defaultMemory); err != nil {
-		if err != http.ErrNotMultipart {
-			return err
-		}
-	}
-	if err := _FormMap(formTagName, obj, req.PostForm); err != nil {
-		return err
+	// 只绑定表单参数，上传文件不绑定到字段，直接删除临时文件
+	files, err := b.Multipart.parse(req)
+	_RemoveUploads(files)
+	if err != nil && err != http.ErrNotMultipart {
+		return _BindError(SourceForm, err)
 	}
-	return Validate(obj)
+	return _BindError(SourceForm, _FormMap(formTagName, obj, req.PostForm))
 }
 
 func _FormParse(ptr interface{}, form map[string][]string) error {
@@ -70,6 +77,5 @@ func _FormMap(tag string, ptr interface{}, form map[string][]string) error {
 		return _FormParse(ptr, form)
 	}
 
-	_, err := _Mapping(reflect.ValueOf(ptr), _EmptyField, _FormSource(form), tag)
-	return err
+	return _PlanBind(ptr, _FormSource(form), tag, false)
 }
@@ -2,21 +2,25 @@ package request
 
 import (
 	"net/http"
-	"net/textproto"
-	"reflect"
 )
 
 type _HeaderSource map[string][]string
 
-func (hs _HeaderSource) TrySet(value reflect.Value, field reflect.StructField, tagValue string, opt _SetOptions) (isSetted bool, err error) {
-	return _ValueSet(value, field, hs, textproto.CanonicalMIMEHeaderKey(tagValue), opt)
+type HeaderBind struct {
+	Validator Validator // 为空时使用 Validate
 }
 
-type HeaderBind struct{}
-
-func (HeaderBind) Bind(req *http.Request, obj interface{}) error {
-	if _, err := _Mapping(reflect.ValueOf(obj), _EmptyField, _HeaderSource(req.Header), "header"); err != nil {
+func (b HeaderBind) Bind(req *http.Request, obj interface{}) error {
+	if err := b.bind(req, obj); err != nil {
 		return err
 	}
-	return Validate(obj)
+	return _Validate(b.Validator, obj)
+}
+
+func (b HeaderBind) validator() Validator {
+	return b.Validator
+}
+
+func (HeaderBind) bind(req *http.Request, obj interface{}) error {
+	return _BindError(SourceHeader, _PlanBind(obj, _HeaderSource(req.Header), "header", false))
 }
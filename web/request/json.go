@@ -7,13 +7,30 @@ import (
 	"net/http"
 )
 
-type JsonBind struct{}
+type JsonBind struct {
+	Validator Validator // 为空时使用 Validate
+}
+
+func (b JsonBind) Bind(req *http.Request, obj interface{}) error {
+	if err := b.bind(req, obj); err != nil {
+		return err
+	}
+	return _Validate(b.Validator, obj)
+}
 
-func (JsonBind) Bind(req *http.Request, obj interface{}) error {
+func (b JsonBind) validator() Validator {
+	return b.Validator
+}
+
+func (JsonBind) bind(req *http.Request, obj interface{}) error {
 	if req == nil || req.Body == nil {
 		return fmt.Errorf("invalid request")
 	}
-	return _JsonDecode(req.Body, obj)
+	// 空请求体不绑定
+	if err := _JsonDecode(req.Body, obj); err != io.EOF {
+		return _BindError(SourceJson, err)
+	}
+	return nil
 }
 
 func _JsonDecode(r io.Reader, obj interface{}) error {
@@ -24,8 +41,5 @@ func _JsonDecode(r io.Reader, obj interface{}) error {
 	if JsonEnableDecoderDisallowUnknownFields {
 		decoder.DisallowUnknownFields()
 	}
-	if err := decoder.Decode(obj); err != nil {
-		return err
-	}
-	return Validate(obj)
+	return decoder.Decode(obj)
 }
@@ -0,0 +1,32 @@
+package request
+
+import (
+	"fmt"
+	"github.com/vmihailenco/msgpack/v5"
+	"net/http"
+)
+
+type MsgPackBind struct {
+	Validator Validator // 为空时使用 Validate
+}
+
+func (b MsgPackBind) Bind(req *http.Request, obj interface{}) error {
+	if err := b.bind(req, obj); err != nil {
+		return err
+	}
+	return _Validate(b.Validator, obj)
+}
+
+func (b MsgPackBind) validator() Validator {
+	return b.Validator
+}
+
+func (MsgPackBind) bind(req *http.Request, obj interface{}) error {
+	if req == nil || req.Body == nil {
+		return fmt.Errorf("invalid request")
+	}
+	decoder := msgpack.NewDecoder(req.Body)
+	// 字段未设置 msgpack 标签时使用 json 标签
+	decoder.SetCustomStructTag("json")
+	return _BindError(SourceMsgPack, decoder.Decode(obj))
+}
@@ -0,0 +1,50 @@
+package request
+
+import (
+	"context"
+	"net/http"
+)
+
+const uriTagName = "uri"
+
+// PathParams 路由捕获的路径参数
+type PathParams map[string]string
+
+type _PathParamsKey struct{}
+
+// WithPathParams 将路径参数保存到请求 context
+func WithPathParams(req *http.Request, ps PathParams) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), _PathParamsKey{}, ps))
+}
+
+// GetPathParams 获取请求的路径参数
+func GetPathParams(req *http.Request) PathParams {
+	ps, _ := req.Context().Value(_PathParamsKey{}).(PathParams)
+	return ps
+}
+
+// PathParam 获取指定名称的路径参数
+func PathParam(req *http.Request, key string) string {
+	return GetPathParams(req)[key]
+}
+
+type _PathSource PathParams
+
+type PathBind struct {
+	Validator Validator // 为空时使用 Validate
+}
+
+func (b PathBind) Bind(req *http.Request, obj interface{}) error {
+	if err := b.bind(req, obj); err != nil {
+		return err
+	}
+	return _Validate(b.Validator, obj)
+}
+
+func (b PathBind) validator() Validator {
+	return b.Validator
+}
+
+func (PathBind) bind(req *http.Request, obj interface{}) error {
+	return _BindError(SourceUri, _PlanBind(obj, _PathSource(GetPathParams(req)), uriTagName, false))
+}
@@ -0,0 +1,381 @@
+package request
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// _Source 按绑定计划取值的数据源
+type _Source interface {
+	Get(key string) ([]string, bool)
+}
+
+// _FileSource 可选接口，数据源包含上传文件
+type _FileSource interface {
+	Files(key string) []*UploadFile
+}
+
+type _ValueSetter func(val string, value reflect.Value) error
+
+type _PlanKey struct {
+	t        reflect.Type
+	tag      string
+	explicit bool
+}
+
+// _Plan 类型的绑定计划，标签、默认值、时间格式及 Unmarshaler 检测只在首次使用时解析
+type _Plan struct {
+	index  int
+	field  reflect.StructField
+	elem   *_Plan   // 指针指向的类型
+	fields []*_Plan // 结构体字段
+
+	trySet      bool
+	key         string
+	opt         _SetOptions
+	kind        reflect.Kind
+	length      int          // 数组长度
+	unmarshaler bool         // 切片、数组整体实现 SliceUnmarshaler 或 Unmarshaler
+	setter      _ValueSetter // 单个值的设置，切片、数组为元素的设置
+}
+
+var (
+	_Plans sync.Map
+
+	_UnmarshalerType      = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	_SliceUnmarshalerType = reflect.TypeOf((*SliceUnmarshaler)(nil)).Elem()
+	_TimeType             = reflect.TypeOf(time.Time{})
+	_DurationType         = reflect.TypeOf(time.Duration(0))
+)
+
+// _GetPlan 获取类型的绑定计划，不存在时创建并缓存；explicit 为 true 时只绑定设置了 tag 的字段
+func _GetPlan(t reflect.Type, tag string, explicit bool) *_Plan {
+	key := _PlanKey{t: t, tag: tag, explicit: explicit}
+	if v, ok := _Plans.Load(key); ok {
+		return v.(*_Plan)
+	}
+	v, _ := _Plans.LoadOrStore(key, _BuildPlan(t, _EmptyField, tag, explicit))
+	return v.(*_Plan)
+}
+
+// _PlanBind 使用缓存的绑定计划绑定 obj
+func _PlanBind(obj interface{}, src _Source, tag string, explicit bool) error {
+	value := reflect.ValueOf(obj)
+	p := _GetPlan(value.Type(), tag, explicit)
+	if p == nil {
+		return nil
+	}
+	_, err := p.exec(value, src)
+	return err
+}
+
+func _BuildPlan(t reflect.Type, field reflect.StructField, tag string, explicit bool) *_Plan {
+	if field.Tag.Get(tag) == "-" { // just ignoring this field
+		return nil
+	}
+
+	p := &_Plan{field: field, kind: t.Kind()}
+	if p.kind == reflect.Ptr {
+		p.elem = _BuildPlan(t.Elem(), field, tag, explicit)
+		return p
+	}
+
+	if _, ok := field.Tag.Lookup(tag); (p.kind != reflect.Struct || !field.Anonymous) && (ok || !explicit) {
+		var opts string
+		p.key, opts = head(field.Tag.Get(tag), ",")
+		if p.key == "" { // default value is FieldName
+			p.key = field.Name
+		}
+		if tag == "header" {
+			p.key = textproto.CanonicalMIMEHeaderKey(p.key)
+		}
+		var opt string
+		for len(opts) > 0 {
+			opt, opts = head(opts, ",")
+			if k, v := head(opt, "="); k == "default" {
+				p.opt.isDefaultExists = true
+				p.opt.defaultValue = v
+			}
+		}
+		if p.key != "" {
+			p.trySet = true
+			switch p.kind {
+			case reflect.Slice, reflect.Array:
+				p.unmarshaler = _Implements(t, _SliceUnmarshalerType) || _Implements(t, _UnmarshalerType)
+				if p.kind == reflect.Array {
+					p.length = t.Len()
+				}
+				p.setter = _BuildSetter(t.Elem(), field)
+			default:
+				p.setter = _BuildSetter(t, field)
+			}
+		}
+	}
+
+	if p.kind == reflect.Struct {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.PkgPath != "" && !sf.Anonymous { // unexported
+				continue
+			}
+			if c := _BuildPlan(sf.Type, sf, tag, explicit); c != nil {
+				c.index = i
+				p.fields = append(p.fields, c)
+			}
+		}
+	}
+	return p
+}
+
+// _Implements 与 _ValueUnmarshaler 一致，具名的非指针类型使用其指针类型判断
+func _Implements(t, it reflect.Type) bool {
+	if t.Kind() != reflect.Ptr && t.Name() != "" {
+		t = reflect.PointerTo(t)
+	}
+	return t.NumMethod() > 0 && t.Implements(it)
+}
+
+func _Addr(v reflect.Value) reflect.Value {
+	if v.Kind() != reflect.Ptr && v.Type().Name() != "" && v.CanAddr() {
+		return v.Addr()
+	}
+	return v
+}
+
+// _BuildSetter 与 _SetValue 一致，按类型预先确定设置方式
+func _BuildSetter(t reflect.Type, field reflect.StructField) _ValueSetter {
+	if _Implements(t, _UnmarshalerType) {
+		return func(val string, value reflect.Value) error {
+			if u, ok := _Addr(value).Interface().(Unmarshaler); ok {
+				return u.UnmarshalForm(val)
+			}
+			return _SetValue(val, value, field)
+		}
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if t == _DurationType {
+			return _SetTimeDuration
+		}
+		bitSize := t.Bits()
+		if t.Kind() == reflect.Int {
+			bitSize = 0
+		}
+		return func(val string, value reflect.Value) error { return _SetIntField(val, bitSize, value) }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		bitSize := t.Bits()
+		if t.Kind() == reflect.Uint {
+			bitSize = 0
+		}
+		return func(val string, value reflect.Value) error { return _SetUintField(val, bitSize, value) }
+	case reflect.Bool:
+		return _SetBoolField
+	case reflect.Float32, reflect.Float64:
+		bitSize := t.Bits()
+		return func(val string, value reflect.Value) error { return _SetFloatField(val, bitSize, value) }
+	case reflect.String:
+		return func(val string, value reflect.Value) error { value.SetString(val); return nil }
+	case reflect.Struct:
+		if t == _TimeType {
+			return _BuildTimeSetter(field)
+		}
+		return func(val string, value reflect.Value) error {
+			return json.Unmarshal(StringToBytes(val), value.Addr().Interface())
+		}
+	case reflect.Map:
+		return func(val string, value reflect.Value) error {
+			return json.Unmarshal(StringToBytes(val), value.Addr().Interface())
+		}
+	}
+	return func(string, reflect.Value) error { return ErrUnknownType }
+}
+
+// _BuildTimeSetter 与 _SetTimeField 一致，预先解析时间格式及时区
+func _BuildTimeSetter(field reflect.StructField) _ValueSetter {
+	timeFormat := field.Tag.Get("time_format")
+	if timeFormat == "" {
+		timeFormat = time.RFC3339
+	}
+	switch tf := strings.ToLower(timeFormat); tf {
+	case "unix", "unixnano":
+		d := time.Duration(1)
+		if tf == "unixnano" {
+			d = time.Second
+		}
+		return func(val string, value reflect.Value) error {
+			tv, err := strconv.ParseInt(val, 10, 64)
+			if err != nil {
+				return err
+			}
+			value.Set(reflect.ValueOf(time.Unix(tv/int64(d), tv%int64(d))))
+			return nil
+		}
+	}
+
+	l := time.Local
+	if isUTC, _ := strconv.ParseBool(field.Tag.Get("time_utc")); isUTC {
+		l = time.UTC
+	}
+	var locErr error
+	if locTag := field.Tag.Get("time_location"); locTag != "" {
+		l, locErr = time.LoadLocation(locTag)
+	}
+	return func(val string, value reflect.Value) error {
+		if val == "" {
+			value.Set(reflect.ValueOf(time.Time{}))
+			return nil
+		}
+		if locErr != nil {
+			return locErr
+		}
+		t, err := time.ParseInLocation(timeFormat, val, l)
+		if err != nil {
+			return err
+		}
+		value.Set(reflect.ValueOf(t))
+		return nil
+	}
+}
+
+// _FieldPath 字段在错误路径中的名称，与 TagValidator 一致，嵌入字段不出现在路径中
+func _FieldPath(sf reflect.StructField) string {
+	if sf.Anonymous {
+		return ""
+	}
+	return sf.Name
+}
+
+// exec 执行绑定计划
+func (p *_Plan) exec(value reflect.Value, src _Source) (bool, error) {
+	if p.elem != nil {
+		var isNew bool
+		vPtr := value
+		if value.IsNil() {
+			isNew = true
+			vPtr = reflect.New(value.Type().Elem())
+		}
+		isSetted, err := p.elem.exec(vPtr.Elem(), src)
+		if err != nil {
+			return false, err
+		}
+		if isNew && isSetted {
+			value.Set(vPtr)
+		}
+		return isSetted, nil
+	}
+	if p.kind == reflect.Ptr {
+		return false, nil
+	}
+
+	if p.trySet {
+		ok, err := p.set(value, src)
+		if err != nil {
+			if _, is := err.(*FieldError); !is {
+				err = &FieldError{Field: p.key, Reason: err.Error()}
+			}
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+
+	var (
+		isSetted bool
+		errs     BindError
+	)
+	for _, f := range p.fields {
+		ok, err := f.exec(value.Field(f.index), src)
+		if err != nil {
+			// 字段绑定错误继续绑定其他字段，以便返回所有出错字段
+			if !errs.add(_FieldPath(f.field), err) {
+				return false, err
+			}
+			continue
+		}
+		isSetted = isSetted || ok
+	}
+	if len(errs.Fields) > 0 {
+		return false, &errs
+	}
+	return isSetted, nil
+}
+
+// set 设置单个字段，切片、数组按多个值设置
+func (p *_Plan) set(value reflect.Value, src _Source) (isSetted bool, err error) {
+	if fs, ok := src.(_FileSource); ok {
+		if files := fs.Files(p.key); len(files) != 0 {
+			return _UploadFileSet(value, p.field, files)
+		}
+	}
+
+	vs, ok := src.Get(p.key)
+	if !ok && !p.opt.isDefaultExists {
+		return false, nil
+	}
+	raw := p.opt.defaultValue
+	if ok {
+		raw = strings.Join(vs, ",")
+	} else {
+		vs = []string{p.opt.defaultValue}
+	}
+	defer func() {
+		if err != nil {
+			err = &FieldError{Field: p.key, Value: raw, Reason: _FieldReason(err)}
+		}
+	}()
+
+	switch p.kind {
+	case reflect.Slice, reflect.Array:
+		if p.unmarshaler {
+			_, err = _SliceUnmarshaler(value, vs)
+			return true, err
+		}
+		target := value
+		if p.kind == reflect.Array {
+			if len(vs) != p.length {
+				return false, fmt.Errorf("%q is not valid value for %s", vs, value.Type().String())
+			}
+		} else {
+			target = reflect.MakeSlice(value.Type(), len(vs), len(vs))
+		}
+		for i, s := range vs {
+			if err = p.setter(s, target.Index(i)); err != nil {
+				return true, err
+			}
+		}
+		if p.kind == reflect.Slice {
+			value.Set(target)
+		}
+		return true, nil
+	default:
+		var val string
+		if len(vs) > 0 {
+			val = vs[0]
+		}
+		return true, p.setter(val, value)
+	}
+}
+
+func (form _FormSource) Get(key string) ([]string, bool) {
+	vs, ok := form[key]
+	return vs, ok
+}
+
+func (hs _HeaderSource) Get(key string) ([]string, bool) {
+	vs, ok := hs[key]
+	return vs, ok
+}
+
+func (ps _PathSource) Get(key string) ([]string, bool) {
+	if v, ok := ps[key]; ok {
+		return []string{v}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,52 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+)
+
+var (
+	ErrNotProtoMessage = errors.New("obj is not proto.Message")
+	ErrBodyTooLarge    = errors.New("request body too large")
+)
+
+type ProtoBind struct {
+	Validator   Validator // 为空时使用 Validate
+	MaxBodySize int64     // 请求体大小限制，为 0 时使用 32M
+}
+
+func (b ProtoBind) Bind(req *http.Request, obj interface{}) error {
+	if err := b.bind(req, obj); err != nil {
+		return err
+	}
+	return _Validate(b.Validator, obj)
+}
+
+func (b ProtoBind) validator() Validator {
+	return b.Validator
+}
+
+func (b ProtoBind) bind(req *http.Request, obj interface{}) error {
+	if req == nil || req.Body == nil {
+		return fmt.Errorf("invalid request")
+	}
+	msg, ok := obj.(proto.Message)
+	if !ok {
+		return ErrNotProtoMessage
+	}
+	limit := b.MaxBodySize
+	if limit <= 0 {
+		limit = defaultMemory
+	}
+	data, err := io.ReadAll(io.LimitReader(req.Body, limit+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > limit {
+		return _BindError(SourceProto, &FieldError{Reason: ErrBodyTooLarge.Error()})
+	}
+	return _BindError(SourceProto, proto.Unmarshal(data, msg))
+}
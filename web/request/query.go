@@ -2,13 +2,26 @@ package request
 
 import "net/http"
 
+// QueryBind 绑定 query 参数，先按 form 标签绑定，再按 query 标签绑定，后绑定的优先
 type QueryBind struct {
+	Validator Validator // 为空时使用 Validate
 }
 
-func (QueryBind) Bind(req *http.Request, obj interface{}) error {
-	values := req.URL.Query()
-	if err := _FormMap(formTagName, obj, values); err != nil {
+func (b QueryBind) Bind(req *http.Request, obj interface{}) error {
+	if err := b.bind(req, obj); err != nil {
 		return err
 	}
-	return Validate(obj)
+	return _Validate(b.Validator, obj)
+}
+
+func (b QueryBind) validator() Validator {
+	return b.Validator
+}
+
+func (QueryBind) bind(req *http.Request, obj interface{}) error {
+	query := req.URL.Query()
+	if err := _FormMap(formTagName, obj, query); err != nil {
+		return _BindError(SourceQuery, err)
+	}
+	return _BindError(SourceQuery, _PlanBind(obj, _FormSource(query), queryTagName, true))
 }
@@ -0,0 +1,265 @@
+package request
+
+import (
+	"bytes"
+	"errors"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type _BenchInner struct {
+	Size int    `form:"size,default=20"`
+	Sort string `form:"sort"`
+}
+
+type _BenchReq struct {
+	_BenchInner
+	ID      int64         `form:"id"`
+	Name    string        `form:"name"`
+	Tags    []string      `form:"tags"`
+	Scores  [2]float64    `form:"scores"`
+	Enable  bool          `form:"enable"`
+	Begin   time.Time     `form:"begin" time_format:"2006-01-02" time_utc:"true"`
+	At      time.Time     `form:"at" time_format:"unix"`
+	Wait    time.Duration `form:"wait"`
+	Page    *_BenchInner
+	Extra   map[string]int `form:"extra"`
+	Ignore  string         `form:"-"`
+	private string
+}
+
+var _BenchForm = _FormSource{
+	"id":     {"10"},
+	"name":   {"gsf"},
+	"tags":   {"a", "b", "c"},
+	"scores": {"1.5", "2.5"},
+	"enable": {"true"},
+	"begin":  {"2022-01-02"},
+	"at":     {"1640995200"},
+	"wait":   {"1m"},
+	"sort":   {"-id"},
+	"extra":  {`{"a":1}`},
+	"Ignore": {"x"},
+}
+
+func TestPlanBind(t *testing.T) {
+	var plan _BenchReq
+	if err := _PlanBind(&plan, _BenchForm, formTagName, false); err != nil {
+		t.Fatal(err)
+	}
+	begin := time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)
+	want := _BenchReq{
+		_BenchInner: _BenchInner{Size: 20, Sort: "-id"},
+		ID:          10,
+		Name:        "gsf",
+		Tags:        []string{"a", "b", "c"},
+		Scores:      [2]float64{1.5, 2.5},
+		Enable:      true,
+		Begin:       begin,
+		At:          time.Unix(1640995200, 0),
+		Wait:        time.Minute,
+		Page:        &_BenchInner{Size: 20, Sort: "-id"},
+		Extra:       map[string]int{"a": 1},
+	}
+	if !reflect.DeepEqual(plan, want) {
+		t.Errorf("plan: %+v\nwant: %+v", plan, want)
+	}
+
+	bad := _FormSource{"id": {"x"}, "scores": {"1"}, "size": {"x"}}
+	errPlan := _PlanBind(&plan, bad, formTagName, false)
+	if errPlan == nil {
+		t.Error("plan should return error")
+	}
+
+	// 错误路径与 TagValidator 一致，不包含嵌入字段的类型名
+	paths := make([]string, 0, 4)
+	if be, ok := errPlan.(*BindError); ok {
+		for _, f := range be.Fields {
+			paths = append(paths, f.Path)
+		}
+	}
+	if s := strings.Join(paths, ","); s != "Size,ID,Scores,Page.Size" {
+		t.Errorf("paths: %s", s)
+	}
+}
+
+func BenchmarkPlanBind(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var v _BenchReq
+		_ = _PlanBind(&v, _BenchForm, formTagName, false)
+	}
+}
+
+func TestBind(t *testing.T) {
+	var v struct {
+		ID    int    `uri:"id" json:"id"`
+		Token string `header:"X-Token"`
+		Page  struct {
+			Size int `query:"size"`
+		}
+		Name  string `json:"name" form:"name"`
+		Other string
+	}
+	req := httptest.NewRequest(http.MethodPost, "/users/7?size=10&name=query&Other=x", strings.NewReader(`{"id":1,"name":"json"}`))
+	req.Header.Set("Content-Type", MIMEJson)
+	req.Header.Set("X-Token", "t")
+	req = WithPathParams(req, PathParams{"id": "7"})
+	if err := Bind(req, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.ID != 7 || v.Token != "t" || v.Page.Size != 10 || v.Name != "query" || v.Other != "" {
+		t.Errorf("bind: %+v", v)
+	}
+
+	// QueryBind 同样支持 query 标签
+	v.Page.Size, v.Name = 0, ""
+	if err := (QueryBind{}).Bind(httptest.NewRequest(http.MethodGet, "/?size=20&name=query", nil), &v); err != nil || v.Page.Size != 20 || v.Name != "query" {
+		t.Errorf("query bind: %+v %v", v, err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/?size=x", nil)
+	req.Header.Set("X-Token", "t")
+	err := Bind(req, &v)
+	if be, ok := err.(*BindError); !ok || len(be.Fields) != 1 || be.Fields[0].Path != "Page.Size" || be.Fields[0].Source != SourceQuery {
+		t.Errorf("bind error: %v", err)
+	}
+}
+
+func _TestMultipartRequest(t *testing.T, files map[string]string) *http.Request {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	_ = mw.WriteField("name", "gsf")
+	for k, v := range files {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", `form-data; name="file"; filename="`+k+`"`)
+		h.Set("Content-Type", "text/plain")
+		w, err := mw.CreatePart(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(v))
+	}
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestMultipartOption(t *testing.T) {
+	dir := t.TempDir()
+	var v struct {
+		Name string        `form:"name"`
+		File []*UploadFile `form:"file"`
+	}
+	b := FormMultipartBind{Multipart: MultipartOption{MaxMemory: 4, TempDir: dir, AllowedTypes: []string{"text/*"}}}
+	if err := b.Bind(_TestMultipartRequest(t, map[string]string{"a.txt": "hello world"}), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Name != "gsf" || len(v.File) != 1 || v.File[0].Size != 11 {
+		t.Fatalf("bind: %+v", v)
+	}
+	if items, _ := os.ReadDir(dir); len(items) != 1 {
+		t.Errorf("temp file should be written to TempDir: %d", len(items))
+	}
+	f, err := v.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(f)
+	_ = f.Close()
+	if string(data) != "hello world" {
+		t.Errorf("file: %s", data)
+	}
+	if err = v.File[0].Remove(); err != nil {
+		t.Error(err)
+	}
+
+	// 校验失败时删除临时文件
+	var invalid struct {
+		File []*UploadFile `form:"file"`
+		Code string        `form:"code" validate:"required"`
+	}
+	if err = BindWith(_TestMultipartRequest(t, map[string]string{"a.txt": "hello world"}), &invalid, b); err == nil {
+		t.Error("code should be required")
+	}
+	if items, _ := os.ReadDir(dir); len(items) != 0 {
+		t.Errorf("temp file should be removed: %d", len(items))
+	}
+
+	b.Multipart.AllowedTypes = []string{"image/*"}
+	if err = b.Bind(_TestMultipartRequest(t, map[string]string{"a.txt": "hello"}), &v); err == nil {
+		t.Error("file type should not be allowed")
+	}
+
+	var streamed string
+	b.Multipart = MultipartOption{MaxFileSize: 5, Stream: func(field string, file *UploadFile, r io.Reader) error {
+		data, err := io.ReadAll(r)
+		streamed += field + ":" + file.Filename + ":" + string(data)
+		return err
+	}}
+	if err = b.Bind(_TestMultipartRequest(t, map[string]string{"a.txt": "hello"}), &v); err != nil || streamed != "file:a.txt:hello" {
+		t.Errorf("stream: %s %v", streamed, err)
+	}
+	if err = b.Bind(_TestMultipartRequest(t, map[string]string{"a.txt": "hello world"}), &v); err == nil {
+		t.Error("file should be too large")
+	}
+}
+
+func TestBodyBind(t *testing.T) {
+	type user struct {
+		Name string `json:"name" xml:"name" yaml:"name" validate:"required"`
+	}
+	mp, _ := msgpack.Marshal(map[string]string{"name": "gsf"})
+	for ct, body := range map[string]string{
+		MIMEXml + "; charset=utf-8": `<user><name>gsf</name></user>`,
+		MIMEYaml2:                   "name: gsf\n",
+		MIMEMsgPack:                 string(mp),
+	} {
+		b, ok := BinderOf(ct)
+		if !ok {
+			t.Errorf("binder not find: %s", ct)
+			continue
+		}
+		var v user
+		if err := b.Bind(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)), &v); err != nil || v.Name != "gsf" {
+			t.Errorf("%s: %+v %v", ct, v, err)
+		}
+	}
+
+	if err := (XMLBind{}).Bind(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`<user></user>`)), &user{}); err == nil {
+		t.Error("name should be required")
+	}
+
+	data, _ := proto.Marshal(wrapperspb.String("gsf"))
+	var msg wrapperspb.StringValue
+	b := Default(http.MethodPost, MIMEProtobuf)
+	if err := b.Bind(httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data)), &msg); err != nil || msg.Value != "gsf" {
+		t.Errorf("protobuf: %s %v", msg.Value, err)
+	}
+
+	// 类型错误及请求体超限转换为 BindError 并设置来源
+	var age struct {
+		Age int `yaml:"age"`
+	}
+	for source, err := range map[string]error{
+		SourceYaml:  (YAMLBind{}).Bind(httptest.NewRequest(http.MethodPost, "/", strings.NewReader("age: x\n")), &age),
+		SourceProto: (ProtoBind{MaxBodySize: 2}).Bind(httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data)), &msg),
+	} {
+		var be *BindError
+		if !errors.As(err, &be) || be.Fields[0].Source != source {
+			t.Errorf("%s: %v", source, err)
+		}
+	}
+}
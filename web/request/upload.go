@@ -0,0 +1,340 @@
+package request
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"reflect"
+	"strings"
+)
+
+var (
+	ErrFileTooLarge       = errors.New("file too large")
+	ErrFileTypeNotAllowed = errors.New("file type not allowed")
+	ErrFileHeaderMissing  = errors.New("multipart.FileHeader is unavailable when TempDir or Stream is set, use UploadFile")
+)
+
+// MultipartOption multipart 请求的解析配置，零值与原 ParseMultipartForm(32MB) 一致
+type MultipartOption struct {
+	MaxMemory    int64    // 上传文件保存在内存中的最大字节数，超出部分写入临时文件，0 时为 32MB
+	MaxSize      int64    // 请求体最大字节数，0 不限制
+	MaxFileSize  int64    // 单个文件最大字节数，0 不限制
+	AllowedTypes []string // 允许的文件 Content-Type，支持 image/* 形式，为空不限制
+	TempDir      string   // 临时文件目录，为空时使用 os.TempDir；设置后文件字段需使用 UploadFile 类型，临时文件需调用 UploadFile.Remove 删除
+
+	// Stream 流式处理上传文件，设置后文件按顺序交给 Stream 处理且不再绑定到字段，
+	// r 超出 MaxFileSize 时返回 ErrFileTooLarge
+	Stream func(field string, file *UploadFile, r io.Reader) error
+}
+
+// UploadFile 上传文件，可绑定 UploadFile、*UploadFile 及其切片、数组类型的字段
+type UploadFile struct {
+	Filename string
+	Header   textproto.MIMEHeader
+	Size     int64
+
+	fh      *multipart.FileHeader
+	content []byte
+	tmpfile string
+}
+
+type _BytesFile struct {
+	*io.SectionReader
+}
+
+func (_BytesFile) Close() error {
+	return nil
+}
+
+func (f *UploadFile) Open() (multipart.File, error) {
+	switch {
+	case f.fh != nil:
+		return f.fh.Open()
+	case f.tmpfile != "":
+		return os.Open(f.tmpfile)
+	}
+	return _BytesFile{io.NewSectionReader(bytes.NewReader(f.content), 0, int64(len(f.content)))}, nil
+}
+
+// Remove 删除写入 TempDir 的临时文件，绑定失败时由绑定删除，绑定成功后由调用方在请求处理完成后删除
+func (f *UploadFile) Remove() error {
+	if f.tmpfile == "" {
+		return nil
+	}
+	err := os.Remove(f.tmpfile)
+	f.tmpfile = ""
+	return err
+}
+
+// FileHeader 标准库解析时的文件信息，设置 TempDir 或 Stream 时为 nil
+func (f *UploadFile) FileHeader() *multipart.FileHeader {
+	return f.fh
+}
+
+// _UploadSource multipart 请求的参数及上传文件
+type _UploadSource struct {
+	_FormSource
+	files map[string][]*UploadFile
+}
+
+func (s _UploadSource) Files(key string) []*UploadFile {
+	return s.files[key]
+}
+
+func (o *MultipartOption) allowed(contentType string) bool {
+	if len(o.AllowedTypes) == 0 {
+		return true
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	contentType, _ = head(contentType, ";")
+	for _, v := range o.AllowedTypes {
+		if v == contentType || (strings.HasSuffix(v, "/*") && strings.HasPrefix(contentType, v[:len(v)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *MultipartOption) check(field string, f *UploadFile) error {
+	if !o.allowed(f.Header.Get("Content-Type")) {
+		return &FieldError{Field: field, Value: f.Filename, Reason: ErrFileTypeNotAllowed.Error()}
+	}
+	if o.MaxFileSize > 0 && f.Size > o.MaxFileSize {
+		return &FieldError{Field: field, Value: f.Filename, Reason: ErrFileTooLarge.Error()}
+	}
+	return nil
+}
+
+// parse 解析 multipart 请求，未设置 TempDir 及 Stream 时使用 ParseMultipartForm
+func (o MultipartOption) parse(req *http.Request) (map[string][]*UploadFile, error) {
+	maxMemory := o.MaxMemory
+	if maxMemory <= 0 {
+		maxMemory = defaultMemory
+	}
+	if req.MultipartForm == nil && o.MaxSize > 0 && req.Body != nil {
+		req.Body = http.MaxBytesReader(nil, req.Body, o.MaxSize)
+	}
+	if req.MultipartForm != nil || (o.TempDir == "" && o.Stream == nil) {
+		if err := req.ParseMultipartForm(maxMemory); err != nil {
+			return nil, err
+		}
+		errs, files := &BindError{}, make(map[string][]*UploadFile, len(req.MultipartForm.File))
+		for k, fhs := range req.MultipartForm.File {
+			for _, fh := range fhs {
+				f := &UploadFile{Filename: fh.Filename, Header: fh.Header, Size: fh.Size, fh: fh}
+				if err := o.check(k, f); err != nil {
+					errs.add("", err)
+				}
+				files[k] = append(files[k], f)
+			}
+		}
+		if len(errs.Fields) > 0 {
+			return nil, errs
+		}
+		return files, nil
+	}
+
+	if err := req.ParseForm(); err != nil {
+		return nil, err
+	}
+	mr, err := req.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	files, err := o.read(mr, maxMemory, req.PostForm)
+	if err != nil {
+		return nil, err
+	}
+	req.MultipartForm = &multipart.Form{Value: map[string][]string(req.PostForm), File: map[string][]*multipart.FileHeader{}}
+	for k, vs := range req.PostForm {
+		req.Form[k] = append(req.Form[k], vs...)
+	}
+	return files, nil
+}
+
+// _UploadBinder 可选接口，返回绑定的上传文件，绑定或校验失败时删除临时文件
+type _UploadBinder interface {
+	bindUploads(req *http.Request, obj interface{}) (map[string][]*UploadFile, error)
+}
+
+func _RemoveUploads(files map[string][]*UploadFile) {
+	for _, fs := range files {
+		for _, f := range fs {
+			_ = f.Remove()
+		}
+	}
+}
+
+// _LimitReader 读取超出限制时返回 ErrFileTooLarge
+type _LimitReader struct {
+	r    io.Reader
+	n    int64
+	over bool
+}
+
+func (l *_LimitReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return l.r.Read(p)
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.n {
+		l.over = true
+		return int(l.n), ErrFileTooLarge
+	}
+	l.n -= int64(n)
+	return n, err
+}
+
+// read 与 multipart.Reader.ReadForm 类似，文件超出内存限制时写入 TempDir
+func (o *MultipartOption) read(mr *multipart.Reader, maxMemory int64, values map[string][]string) (files map[string][]*UploadFile, err error) {
+	files = make(map[string][]*UploadFile)
+	defer func() {
+		if err != nil {
+			_RemoveUploads(files)
+		}
+	}()
+
+	maxValue := maxMemory + 10<<20
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return files, err
+		}
+		name := p.FormName()
+		if name == "" {
+			continue
+		}
+		filename := p.FileName()
+
+		var b bytes.Buffer
+		if filename == "" {
+			n, err := io.CopyN(&b, p, maxValue+1)
+			if err != nil && err != io.EOF {
+				return files, err
+			}
+			if maxValue -= n; maxValue < 0 {
+				return files, multipart.ErrMessageTooLarge
+			}
+			values[name] = append(values[name], b.String())
+			continue
+		}
+
+		f := &UploadFile{Filename: filename, Header: p.Header}
+		if err = o.check(name, f); err != nil {
+			return files, _BindError(SourceForm, err)
+		}
+		lr := &_LimitReader{r: p, n: -1}
+		if o.MaxFileSize > 0 {
+			lr.n = o.MaxFileSize
+		}
+		if o.Stream != nil {
+			err = o.Stream(name, f, lr)
+			if lr.over {
+				return files, _BindError(SourceForm, &FieldError{Field: name, Value: filename, Reason: ErrFileTooLarge.Error()})
+			}
+			if err != nil {
+				return files, err
+			}
+			continue
+		}
+
+		n, err := io.CopyN(&b, lr, maxMemory+1)
+		if err != nil && err != io.EOF {
+			if lr.over {
+				err = _BindError(SourceForm, &FieldError{Field: name, Value: filename, Reason: ErrFileTooLarge.Error()})
+			}
+			return files, err
+		}
+		if n > maxMemory {
+			// 超出内存限制，写入临时文件
+			file, err := os.CreateTemp(o.TempDir, "multipart-")
+			if err != nil {
+				return files, err
+			}
+			f.tmpfile = file.Name()
+			files[name] = append(files[name], f)
+			size, err := io.Copy(file, io.MultiReader(&b, lr))
+			if cerr := file.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				if lr.over {
+					err = _BindError(SourceForm, &FieldError{Field: name, Value: filename, Reason: ErrFileTooLarge.Error()})
+				}
+				return files, err
+			}
+			f.Size = size
+			continue
+		}
+		f.content, f.Size = b.Bytes(), n
+		maxMemory -= n
+		files[name] = append(files[name], f)
+	}
+	return files, nil
+}
+
+// _UploadFileSet 上传文件设置到字段，支持 UploadFile 及 multipart.FileHeader
+func _UploadFileSet(value reflect.Value, field reflect.StructField, files []*UploadFile) (isSetted bool, err error) {
+	switch value.Kind() {
+	case reflect.Ptr:
+		switch value.Interface().(type) {
+		case *UploadFile:
+			value.Set(reflect.ValueOf(files[0]))
+			return true, nil
+		case *multipart.FileHeader:
+			if files[0].fh == nil {
+				return false, ErrFileHeaderMissing
+			}
+			value.Set(reflect.ValueOf(files[0].fh))
+			return true, nil
+		}
+	case reflect.Struct:
+		switch value.Interface().(type) {
+		case UploadFile:
+			value.Set(reflect.ValueOf(*files[0]))
+			return true, nil
+		case multipart.FileHeader:
+			if files[0].fh == nil {
+				return false, ErrFileHeaderMissing
+			}
+			value.Set(reflect.ValueOf(*files[0].fh))
+			return true, nil
+		}
+	case reflect.Slice:
+		slice := reflect.MakeSlice(value.Type(), len(files), len(files))
+		isSetted, err = _SetArrayOfUploadFiles(slice, field, files)
+		if err != nil || !isSetted {
+			return isSetted, err
+		}
+		value.Set(slice)
+		return true, nil
+	case reflect.Array:
+		return _SetArrayOfUploadFiles(value, field, files)
+	}
+	return false, errors.New("unsupported field type for UploadFile")
+}
+
+func _SetArrayOfUploadFiles(value reflect.Value, field reflect.StructField, files []*UploadFile) (isSetted bool, err error) {
+	if value.Len() != len(files) {
+		return false, errors.New("unsupported len of array for []*UploadFile")
+	}
+	for i := range files {
+		setted, err := _UploadFileSet(value.Index(i), field, files[i:i+1])
+		if err != nil || !setted {
+			return setted, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,200 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const validateTagName = "validate"
+
+// Validator 绑定完成后的参数校验
+type Validator interface {
+	Validate(obj interface{}) error
+}
+
+// Rule 校验规则，value 为字段值（指针已解引用），param 为规则参数，如 min=1 中的 1
+type Rule func(value reflect.Value, param string) bool
+
+// TagValidator 基于 validate 标签的校验，如 `validate:"required,min=1,max=64,email,oneof=a b"`，
+// 规则之间为且关系，omitempty 表示字段为零值时跳过其他规则，使用未注册的规则时 panic
+type TagValidator struct {
+	rules map[string]Rule
+}
+
+var _DefaultValidator = NewValidator()
+
+var _EmailRegexp = regexp.MustCompile(`^[a-zA-Z0-9.!#$%&'*+/=?^_{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)+$`)
+
+// NewValidator 创建包含内置规则（required, min, max, len, email, oneof）的校验
+func NewValidator() *TagValidator {
+	v := &TagValidator{rules: make(map[string]Rule)}
+	v.Register("required", _RuleRequired)
+	v.Register("min", func(value reflect.Value, param string) bool {
+		return _RuleCompare(value, param, func(a, b float64) bool { return a >= b })
+	})
+	v.Register("max", func(value reflect.Value, param string) bool {
+		return _RuleCompare(value, param, func(a, b float64) bool { return a <= b })
+	})
+	v.Register("len", func(value reflect.Value, param string) bool {
+		return _RuleCompare(value, param, func(a, b float64) bool { return a == b })
+	})
+	v.Register("email", func(value reflect.Value, _ string) bool {
+		return value.Kind() == reflect.String && _EmailRegexp.MatchString(value.String())
+	})
+	v.Register("oneof", func(value reflect.Value, param string) bool {
+		// 经未导出的嵌入字段访问的值不能调用 Interface，由 fmt 直接格式化 reflect.Value
+		s := fmt.Sprint(value)
+		for _, item := range strings.Fields(param) {
+			if item == s {
+				return true
+			}
+		}
+		return false
+	})
+	return v
+}
+
+// Register 注册自定义规则，同名规则会被覆盖，需在校验使用前注册；
+// 经未导出的嵌入字段访问的值 CanInterface 为 false，规则中不能调用 Interface
+func (v *TagValidator) Register(name string, rule Rule) *TagValidator {
+	v.rules[name] = rule
+	return v
+}
+
+func (v *TagValidator) Validate(obj interface{}) error {
+	errs := &BindError{}
+	v.validate(reflect.ValueOf(obj), "", errs)
+	if len(errs.Fields) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validate 与绑定计划一致，遍历指针、结构体、切片、数组及 map
+func (v *TagValidator) validate(value reflect.Value, path string, errs *BindError) {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !value.IsNil() {
+			v.validate(value.Elem(), path, errs)
+		}
+	case reflect.Struct:
+		t := value.Type()
+		for i := 0; i < value.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.PkgPath != "" && !sf.Anonymous { // unexported
+				continue
+			}
+			fPath := sf.Name
+			if path != "" {
+				fPath = path + "." + sf.Name
+			}
+			if sf.Anonymous {
+				fPath = path
+			}
+			if tag := sf.Tag.Get(validateTagName); tag != "" && tag != "-" {
+				v.validateField(value.Field(i), sf, tag, fPath, errs)
+			}
+			v.validate(value.Field(i), fPath, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			v.validate(value.Index(i), path+"["+strconv.Itoa(i)+"]", errs)
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			v.validate(iter.Value(), path+"["+fmt.Sprint(iter.Key())+"]", errs)
+		}
+	}
+}
+
+// validateField 规则属于服务端定义，未注册的规则为配置错误，panic 而不作为请求参数错误返回
+func (v *TagValidator) validateField(value reflect.Value, sf reflect.StructField, tag, path string, errs *BindError) {
+	rules, omitempty := strings.Split(tag, ","), false
+	for _, r := range rules {
+		name, _ := head(r, "=")
+		if name == "omitempty" {
+			omitempty = true
+		} else if _, ok := v.rules[name]; !ok && name != "" {
+			panic(errors.New("Validate Rule Is Not Registered: " + name + ": " + path))
+		}
+	}
+	if omitempty && value.IsZero() {
+		return
+	}
+
+	isNil := (value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) && value.IsNil()
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	for _, r := range rules {
+		name, param := head(r, "=")
+		if name == "" || name == "omitempty" {
+			continue
+		}
+		rule, ok := v.rules[name], true
+		if name == "required" && isNil {
+			ok = false
+		} else if isNil {
+			continue
+		} else {
+			ok = rule(value, param)
+		}
+		if !ok {
+			field, source := _FieldName(sf)
+			raw := ""
+			if !isNil {
+				raw = fmt.Sprint(value)
+			}
+			errs.Fields = append(errs.Fields, &FieldError{Path: path, Field: field, Source: source, Value: raw, Reason: r})
+			return
+		}
+	}
+}
+
+// _FieldName 按 json, form, query, header, uri 的顺序从标签获取参数名及来源
+func _FieldName(sf reflect.StructField) (string, string) {
+	for _, v := range [][2]string{{"json", SourceJson}, {formTagName, SourceForm}, {queryTagName, SourceQuery}, {"header", SourceHeader}, {uriTagName, SourceUri}} {
+		if name, _ := head(sf.Tag.Get(v[0]), ","); name != "" && name != "-" {
+			return name, v[1]
+		}
+	}
+	return sf.Name, ""
+}
+
+func _RuleRequired(value reflect.Value, _ string) bool {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String, reflect.Array:
+		return value.Len() > 0
+	}
+	return !value.IsZero()
+}
+
+// _RuleCompare 字符串按字符数、切片及 map 按长度、数字按值比较
+func _RuleCompare(value reflect.Value, param string, cmp func(a, b float64) bool) bool {
+	p, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return false
+	}
+	var n float64
+	switch value.Kind() {
+	case reflect.String:
+		n = float64(utf8.RuneCountInString(value.String()))
+	case reflect.Slice, reflect.Map, reflect.Array:
+		n = float64(value.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = value.Float()
+	default:
+		return false
+	}
+	return cmp(n, p)
+}
@@ -0,0 +1,29 @@
+package request
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+)
+
+type XMLBind struct {
+	Validator Validator // 为空时使用 Validate
+}
+
+func (b XMLBind) Bind(req *http.Request, obj interface{}) error {
+	if err := b.bind(req, obj); err != nil {
+		return err
+	}
+	return _Validate(b.Validator, obj)
+}
+
+func (b XMLBind) validator() Validator {
+	return b.Validator
+}
+
+func (XMLBind) bind(req *http.Request, obj interface{}) error {
+	if req == nil || req.Body == nil {
+		return fmt.Errorf("invalid request")
+	}
+	return _BindError(SourceXml, xml.NewDecoder(req.Body).Decode(obj))
+}
@@ -0,0 +1,40 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"net/http"
+)
+
+type YAMLBind struct {
+	Validator Validator // 为空时使用 Validate
+}
+
+func (b YAMLBind) Bind(req *http.Request, obj interface{}) error {
+	if err := b.bind(req, obj); err != nil {
+		return err
+	}
+	return _Validate(b.Validator, obj)
+}
+
+func (b YAMLBind) validator() Validator {
+	return b.Validator
+}
+
+func (YAMLBind) bind(req *http.Request, obj interface{}) error {
+	if req == nil || req.Body == nil {
+		return fmt.Errorf("invalid request")
+	}
+	err := yaml.NewDecoder(req.Body).Decode(obj)
+	// 类型不匹配的字段逐个转换为 FieldError
+	var te *yaml.TypeError
+	if errors.As(err, &te) {
+		be := &BindError{}
+		for _, msg := range te.Errors {
+			be.Fields = append(be.Fields, &FieldError{Reason: msg})
+		}
+		err = be
+	}
+	return _BindError(SourceYaml, err)
+}
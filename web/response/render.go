@@ -0,0 +1,141 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	MIMEJson     = "application/json"
+	MIMEXml      = "application/xml"
+	MIMEXml2     = "text/xml"
+	MIMEMsgPack  = "application/msgpack"
+	MIMEMsgPack2 = "application/x-msgpack"
+	MIMEProtobuf = "application/x-protobuf"
+	MIMEProtoBuf = "application/protobuf"
+)
+
+// DataGetter 获取响应数据，用于判断数据是否可按 protobuf 输出
+type DataGetter interface {
+	GetData() interface{}
+}
+
+type _Accept struct {
+	mime string
+	q    float64
+}
+
+// _ParseAccept 解析 Accept 头，按 q 值由高到低排序
+func _ParseAccept(accept string) []_Accept {
+	ret := make([]_Accept, 0, 4)
+	for _, item := range strings.Split(accept, ",") {
+		t, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ret = append(ret, _Accept{mime: t, q: q})
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool { return ret[i].q > ret[j].q })
+	return ret
+}
+
+// Negotiate 根据 Accept 选择输出格式，不支持时使用 JSON；只有数据实现 proto.Message 时才选择 protobuf
+func Negotiate(req *http.Request, ret Response) string {
+	if req == nil {
+		return MIMEJson
+	}
+	_, isProto := ret.(DataGetter)
+	if isProto {
+		_, isProto = ret.(DataGetter).GetData().(proto.Message)
+	}
+	for _, v := range _ParseAccept(req.Header.Get("Accept")) {
+		switch v.mime {
+		case MIMEJson, "application/*", "*/*":
+			return MIMEJson
+		case MIMEXml, MIMEXml2:
+			return MIMEXml
+		case MIMEMsgPack, MIMEMsgPack2:
+			return MIMEMsgPack
+		case MIMEProtobuf, MIMEProtoBuf:
+			if isProto {
+				return MIMEProtobuf
+			}
+		}
+	}
+	return MIMEJson
+}
+
+// Marshal 按 Negotiate 返回的格式编码响应
+func Marshal(format string, ret Response) ([]byte, error) {
+	switch format {
+	case MIMEXml:
+		data, err := xml.Marshal(ret)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(xml.Header), data...), nil
+	case MIMEMsgPack:
+		buf := &bytes.Buffer{}
+		encoder := msgpack.NewEncoder(buf)
+		// 字段未设置 msgpack 标签时使用 json 标签，与 request.MsgPackBind 一致
+		encoder.SetCustomStructTag("json")
+		err := encoder.Encode(ret)
+		return buf.Bytes(), err
+	case MIMEProtobuf:
+		return proto.Marshal(ret.(DataGetter).GetData().(proto.Message))
+	}
+	buf := &bytes.Buffer{}
+	err := json.NewEncoder(buf).Encode(ret)
+	return buf.Bytes(), err
+}
+
+// ContentType 格式对应的 Content-Type，文本格式带 charset
+func ContentType(format string) string {
+	if format == MIMEJson || format == MIMEXml {
+		return format + "; charset=utf-8"
+	}
+	return format
+}
+
+// RenderStatus 按协商的格式输出响应，编码失败时回退为 JSON
+func RenderStatus(w http.ResponseWriter, req *http.Request, status int, ret Response) {
+	format := Negotiate(req, ret)
+	data, err := Marshal(format, ret)
+	if err != nil && format != MIMEJson {
+		format = MIMEJson
+		data, err = Marshal(format, ret)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType(format))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+	if req == nil || req.Method != http.MethodHead {
+		_, _ = w.Write(data)
+	}
+}
+
+// Render 按 Accept 输出响应，HTTP 状态码由 HTTPStatus 决定
+func Render(w http.ResponseWriter, req *http.Request, ret Response) {
+	RenderStatus(w, req, HTTPStatus(ret), ret)
+}
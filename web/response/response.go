@@ -1,15 +1,25 @@
 package response
 
-import "fmt"
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+)
 
 type Error interface {
 	error
 	Code() int
 }
 
+// StatusError 可选接口，错误指定的 HTTP 状态码
+type StatusError interface {
+	HTTPStatus() int
+}
+
 type ErrorDefault struct {
 	ErrMsg  string
 	ErrCode int
+	Status  int // HTTP 状态码，0 时按 CodeHTTPStatus 决定
 }
 
 func (v *ErrorDefault) Error() string {
@@ -20,6 +30,10 @@ func (v *ErrorDefault) Code() int {
 	return v.ErrCode
 }
 
+func (v *ErrorDefault) HTTPStatus() int {
+	return v.Status
+}
+
 type Response interface {
 	SetErrCode(int) Response
 	SetCustomError(Error) Response
@@ -61,9 +75,14 @@ func SetErrCode(c *int, m *string, code int) {
 	}
 }
 
+// MultiError 可选接口，包含多个错误时逐个输出，如 request.BindError
+type MultiError interface {
+	Errors() []error
+}
+
 func SetError(c *[]string, errs ...error) {
 	if ShowMore {
-		if *c != nil {
+		if *c == nil {
 			nLen := 4
 			if len(errs) > nLen {
 				nLen = len(errs)
@@ -71,7 +90,9 @@ func SetError(c *[]string, errs ...error) {
 			*c = make([]string, 0, nLen)
 		}
 		for _, err := range errs {
-			if err != nil {
+			if v, ok := err.(MultiError); ok {
+				SetError(c, v.Errors()...)
+			} else if err != nil {
 				*c = append(*c, err.Error())
 			}
 		}
@@ -79,33 +100,102 @@ func SetError(c *[]string, errs ...error) {
 }
 
 type _Default struct {
-	Code   int         `json:"code"`
-	Msg    string      `json:"msg"`
-	Data   interface{} `json:"data"`
-	Meta   interface{} `json:"meta,omitempty"`
-	Errors []string    `json:"errors,omitempty"`
+	XMLName xml.Name    `json:"-" xml:"response" msgpack:"-"`
+	Code    int         `json:"code" xml:"code" msgpack:"code"`
+	Msg     string      `json:"msg" xml:"msg" msgpack:"msg"`
+	Data    interface{} `json:"data" xml:"data" msgpack:"data"`
+	Meta    interface{} `json:"meta,omitempty" xml:"meta,omitempty" msgpack:"meta,omitempty"`
+	Errors  []string    `json:"errors,omitempty" xml:"errors>error,omitempty" msgpack:"errors,omitempty"`
+
+	status int
 }
 
 var (
 	CodeMsgMap = map[int]string{}
 	ShowMore   = true
+
+	codeStatus []*_CodeStatus
 )
 
+type _CodeStatus struct {
+	min, max, status int
+}
+
+// RegisterCodeStatus 注册 [min, max] 区间的错误码对应的 HTTP 状态码，后注册的优先，需在服务启动前注册，返回取消注册的函数
+func RegisterCodeStatus(min, max, status int) func() {
+	v := &_CodeStatus{min: min, max: max, status: status}
+	codeStatus = append(codeStatus, v)
+	return func() {
+		for i := range codeStatus {
+			if codeStatus[i] == v {
+				codeStatus = append(codeStatus[:i:i], codeStatus[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// CodeHTTPStatus 错误码对应的 HTTP 状态码：使用注册的区间，未注册时为 200
+func CodeHTTPStatus(code int) int {
+	for i := len(codeStatus) - 1; i >= 0; i-- {
+		if v := codeStatus[i]; code >= v.min && code <= v.max {
+			return v.status
+		}
+	}
+	return http.StatusOK
+}
+
+// HTTPStatus 响应的 HTTP 状态码，响应未实现 StatusError 时按 200 处理
+func HTTPStatus(ret Response) int {
+	if v, ok := ret.(StatusError); ok {
+		if status := v.HTTPStatus(); status > 0 {
+			return status
+		}
+	}
+	return http.StatusOK
+}
+
+// WithHTTPStatus 为响应指定 HTTP 状态码，响应不支持时忽略
+func WithHTTPStatus(ret Response, status int) Response {
+	if v, ok := ret.(interface{ SetHTTPStatus(int) Response }); ok {
+		return v.SetHTTPStatus(status)
+	}
+	return ret
+}
+
 func (r *_Default) SetErrCode(code int) Response {
 	SetErrCode(&r.Code, &r.Msg, code)
+	r.status = 0
 	return r
 }
 
 func (r *_Default) SetCustomError(err Error) Response {
 	SetCode(&r.Code, err.Code())
+	r.status = 0
+	if v, ok := err.(StatusError); ok {
+		r.status = v.HTTPStatus()
+	}
 	return r._SetMsg(err.Error())
 }
 
 func (r *_Default) SetErrMsg(code int, msg string, msgArgs ...interface{}) Response {
 	SetCode(&r.Code, code)
+	r.status = 0
 	return r._SetMsg(msg, msgArgs...)
 }
 
+func (r *_Default) SetHTTPStatus(status int) Response {
+	r.status = status
+	return r
+}
+
+func (r *_Default) HTTPStatus() int {
+	if r.status > 0 {
+		return r.status
+	}
+	return CodeHTTPStatus(r.Code)
+}
+
 func (r *_Default) _SetMsg(msg string, msgArgs ...interface{}) Response {
 	SetMsg(&r.Msg, msg, msgArgs...)
 	return r
@@ -132,6 +222,10 @@ func (r *_Default) AddError(errs ...error) Response {
 	return r
 }
 
+func (r *_Default) GetData() interface{} {
+	return r.Data
+}
+
 func New() Response {
 	return &_Default{}
 }
@@ -0,0 +1,190 @@
+package web
+
+import (
+	"errors"
+	"github.com/kzangv/gsf-fof/web/middleware"
+	"github.com/kzangv/gsf-fof/web/request"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrCodeNotFound         = http.StatusNotFound
+	ErrCodeMethodNotAllowed = http.StatusMethodNotAllowed
+)
+
+// _Node 路由树节点，按路径段匹配：静态段优先，其次 :param，最后 *catchAll
+type _Node struct {
+	static   map[string]*_Node
+	param    *_Node
+	wild     *_Node
+	name     string
+	pattern  string
+	handlers map[string]http.Handler
+}
+
+func (n *_Node) child(seg, pattern string) *_Node {
+	switch seg[0] {
+	case ':', '*':
+		name, ptr := seg[1:], &n.param
+		if seg[0] == '*' {
+			ptr = &n.wild
+		}
+		if name == "" {
+			panic(errors.New("Router Param Name Is Empty: " + pattern))
+		}
+		if *ptr == nil {
+			*ptr = &_Node{name: name}
+		} else if (*ptr).name != name {
+			panic(errors.New("Router Param Conflict: " + pattern + " with :" + (*ptr).name))
+		}
+		return *ptr
+	}
+	if n.static == nil {
+		n.static = make(map[string]*_Node)
+	}
+	c, ok := n.static[seg]
+	if !ok {
+		c = &_Node{}
+		n.static[seg] = c
+	}
+	return c
+}
+
+func (n *_Node) match(segs []string, ps request.PathParams) *_Node {
+	if len(segs) == 0 {
+		if len(n.handlers) > 0 {
+			return n
+		}
+		if n.wild != nil {
+			ps[n.wild.name] = ""
+			return n.wild
+		}
+		return nil
+	}
+	if c, ok := n.static[segs[0]]; ok {
+		if ret := c.match(segs[1:], ps); ret != nil {
+			return ret
+		}
+	}
+	if n.param != nil {
+		if ret := n.param.match(segs[1:], ps); ret != nil {
+			ps[n.param.name] = segs[0]
+			return ret
+		}
+	}
+	if n.wild != nil {
+		ps[n.wild.name] = strings.Join(segs, "/")
+		return n.wild
+	}
+	return nil
+}
+
+func (n *_Node) allow() string {
+	ms := make([]string, 0, len(n.handlers))
+	for k := range n.handlers {
+		ms = append(ms, k)
+	}
+	sort.Strings(ms)
+	return strings.Join(ms, ", ")
+}
+
+func _PathSegments(path string) []string {
+	return strings.FieldsFunc(path, func(r rune) bool { return r == '/' })
+}
+
+// RouteGroup 路由分组，分组中间件只作用于分组内注册的路由
+type RouteGroup struct {
+	router *Router
+	prefix string
+	mws    []middleware.Middleware
+}
+
+// Use 添加分组中间件，只对之后注册的路由生效
+func (g *RouteGroup) Use(mws ...middleware.Middleware) *RouteGroup {
+	g.mws = append(g.mws, mws...)
+	return g
+}
+
+// Group 创建子分组，继承当前分组的前缀及中间件
+func (g *RouteGroup) Group(prefix string, mws ...middleware.Middleware) *RouteGroup {
+	ret := &RouteGroup{router: g.router, prefix: g.prefix + "/" + strings.Trim(prefix, "/")}
+	ret.mws = append(append(ret.mws, g.mws...), mws...)
+	return ret
+}
+
+// Handle 注册路由，路径支持 /users/:id 形式的参数及 /static/*path 形式的通配
+func (g *RouteGroup) Handle(method, path string, h http.Handler) {
+	pattern := g.prefix + "/" + strings.Trim(path, "/")
+	segs := _PathSegments(pattern)
+	n := g.router.root
+	for i, seg := range segs {
+		if seg[0] == '*' && i != len(segs)-1 {
+			panic(errors.New("Router Catch-All Must Be Last: " + pattern))
+		}
+		n = n.child(seg, pattern)
+	}
+	if n.handlers == nil {
+		n.handlers = make(map[string]http.Handler)
+	}
+	if _, ok := n.handlers[method]; ok {
+		panic(errors.New("Router Repeat: " + method + " " + pattern))
+	}
+	n.pattern, n.handlers[method] = pattern, middleware.Chain(h, g.mws...)
+}
+
+func (g *RouteGroup) HandleFunc(method, path string, h http.HandlerFunc) {
+	g.Handle(method, path, h)
+}
+
+func (g *RouteGroup) Get(path string, h http.Handler)    { g.Handle(http.MethodGet, path, h) }
+func (g *RouteGroup) Post(path string, h http.Handler)   { g.Handle(http.MethodPost, path, h) }
+func (g *RouteGroup) Put(path string, h http.Handler)    { g.Handle(http.MethodPut, path, h) }
+func (g *RouteGroup) Patch(path string, h http.Handler)  { g.Handle(http.MethodPatch, path, h) }
+func (g *RouteGroup) Delete(path string, h http.Handler) { g.Handle(http.MethodDelete, path, h) }
+
+// Router 路由，可直接作为 WebService.Handler
+type Router struct {
+	RouteGroup
+	NotFound, MethodNotAllowed http.Handler
+
+	root *_Node
+}
+
+func NewRouter() *Router {
+	r := &Router{root: &_Node{}}
+	r.RouteGroup.router = r
+	return r
+}
+
+func (r *Router) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	ps := request.PathParams{}
+	n := r.root.match(_PathSegments(req.URL.Path), ps)
+	if n == nil {
+		if r.NotFound != nil {
+			r.NotFound.ServeHTTP(resp, req)
+		} else {
+			middleware.WriteErrCode(resp, req, http.StatusNotFound, ErrCodeNotFound)
+		}
+		return
+	}
+
+	h, ok := n.handlers[req.Method]
+	if !ok && req.Method == http.MethodHead {
+		h, ok = n.handlers[http.MethodGet]
+	}
+	if !ok {
+		resp.Header().Set("Allow", n.allow())
+		if r.MethodNotAllowed != nil {
+			r.MethodNotAllowed.ServeHTTP(resp, req)
+		} else {
+			middleware.WriteErrCode(resp, req, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed)
+		}
+		return
+	}
+	if len(ps) > 0 {
+		req = request.WithPathParams(req, ps)
+	}
+	h.ServeHTTP(resp, req)
+}
@@ -0,0 +1,268 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/kzangv/gsf-fof/web/request"
+	"github.com/kzangv/gsf-fof/web/response"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	r := NewRouter()
+	r.Get("/users/:id", http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		var v struct {
+			ID   int    `uri:"id"`
+			Kind string `uri:"kind,default=user"`
+		}
+		if err := (request.PathBind{}).Bind(req, &v); err != nil {
+			t.Error(err)
+		}
+		_, _ = resp.Write([]byte(v.Kind + ":" + request.PathParam(req, "id")))
+	}))
+	r.Get("/users/me", http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		_, _ = resp.Write([]byte("me"))
+	}))
+	api := r.Group("/api", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			resp.Header().Set("X-Group", "api")
+			next.ServeHTTP(resp, req)
+		})
+	})
+	api.Post("/files/*path", http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		_, _ = resp.Write([]byte(request.PathParam(req, "path")))
+	}))
+
+	for _, v := range []struct {
+		method, path string
+		status       int
+		body, group  string
+	}{
+		{http.MethodGet, "/users/10", http.StatusOK, "user:10", ""},
+		{http.MethodGet, "/users/me", http.StatusOK, "me", ""},
+		{http.MethodPost, "/api/files/a/b.txt", http.StatusOK, "a/b.txt", "api"},
+		{http.MethodGet, "/api/files/a", http.StatusMethodNotAllowed, "", ""},
+		{http.MethodGet, "/none", http.StatusNotFound, "", ""},
+	} {
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, httptest.NewRequest(v.method, v.path, nil))
+		if resp.Code != v.status {
+			t.Errorf("%s %s status: %d", v.method, v.path, resp.Code)
+			continue
+		}
+		if v.status != http.StatusOK {
+			var body struct {
+				Code int `json:"code"`
+			}
+			if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil || body.Code != v.status {
+				t.Errorf("%s %s body: %s", v.method, v.path, resp.Body.String())
+			}
+			continue
+		}
+		if resp.Body.String() != v.body || resp.Header().Get("X-Group") != v.group {
+			t.Errorf("%s %s body: %s", v.method, v.path, resp.Body.String())
+		}
+	}
+}
+
+type _TestUserReq struct {
+	ID    int    `uri:"id"`
+	Token string `header:"X-Token"`
+	Name  string `json:"name"`
+}
+
+type _TestUserResp struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestHandle(t *testing.T) {
+	r := NewRouter()
+	r.Put("/users/:id", Handle(func(_ context.Context, req *_TestUserReq) (*_TestUserResp, error) {
+		if req.Token != "t" {
+			return nil, &response.ErrorDefault{ErrMsg: "token invalid", ErrCode: 1001, Status: http.StatusUnauthorized}
+		}
+		return &_TestUserResp{ID: req.ID, Name: req.Name}, nil
+	}))
+
+	for _, v := range []struct {
+		token, body string
+		code        int
+		name        string
+		status      int
+	}{
+		{"t", `{"name":"gsf"}`, 0, "gsf", http.StatusOK},
+		{"x", `{"name":"gsf"}`, 1001, "", http.StatusUnauthorized},
+		{"t", `{"name":`, ErrCodeBind, "", http.StatusBadRequest},
+		{"t", `{"id":1,"name":"gsf"}`, 0, "gsf", http.StatusOK},
+		{"t", ``, 0, "", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/users/7", strings.NewReader(v.body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Token", v.token)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		if resp.Code != v.status {
+			t.Errorf("status: %d", resp.Code)
+		}
+
+		var body struct {
+			Code int           `json:"code"`
+			Msg  string        `json:"msg"`
+			Data _TestUserResp `json:"data"`
+		}
+		if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+			t.Error(err)
+			continue
+		}
+		if body.Code != v.code || body.Data.Name != v.name || (v.code == 0 && body.Data.ID != 7) {
+			t.Errorf("body: %s", resp.Body.String())
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	for _, v := range []struct {
+		accept, contentType string
+		data                interface{}
+	}{
+		{"", response.MIMEJson, "gsf"},
+		{"text/html, application/xml;q=0.9", response.MIMEXml, "gsf"},
+		{"application/x-msgpack", response.MIMEMsgPack, "gsf"},
+		{"application/x-msgpack", response.MIMEMsgPack, &_TestUserResp{ID: 7, Name: "gsf"}},
+		{"application/x-protobuf, application/json;q=0.5", response.MIMEJson, "gsf"},
+		{"application/x-protobuf", response.MIMEProtobuf, wrapperspb.String("gsf")},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", v.accept)
+		resp := httptest.NewRecorder()
+		response.Render(resp, req, response.New().SetData(v.data))
+		if ct := resp.Header().Get("Content-Type"); !strings.HasPrefix(ct, v.contentType) {
+			t.Errorf("accept: %s, content type: %s", v.accept, ct)
+			continue
+		}
+
+		switch v.contentType {
+		case response.MIMEMsgPack:
+			var body struct {
+				Data interface{} `msgpack:"data"`
+			}
+			err := msgpack.Unmarshal(resp.Body.Bytes(), &body)
+			// 未设置 msgpack 标签的字段使用 json 标签
+			if m, ok := body.Data.(map[string]interface{}); ok {
+				if _, ok = m["id"]; !ok {
+					t.Errorf("msgpack should use json tag: %v", m)
+				}
+				body.Data = m["name"]
+			}
+			if err != nil || body.Data != "gsf" {
+				t.Errorf("msgpack: %v %v", body, err)
+			}
+		case response.MIMEProtobuf:
+			var body wrapperspb.StringValue
+			if err := proto.Unmarshal(resp.Body.Bytes(), &body); err != nil || body.Value != "gsf" {
+				t.Errorf("protobuf: %v %v", body.Value, err)
+			}
+		default:
+			if !strings.Contains(resp.Body.String(), "gsf") {
+				t.Errorf("body: %s", resp.Body.String())
+			}
+		}
+	}
+}
+
+func TestCodeHTTPStatus(t *testing.T) {
+	unregister := response.RegisterCodeStatus(10000, 19999, http.StatusBadRequest)
+	for code, status := range map[int]int{0: http.StatusOK, 404: http.StatusOK, 10001: http.StatusBadRequest, 20001: http.StatusOK} {
+		if v := response.HTTPStatus(response.New().SetErrCode(code)); v != status {
+			t.Errorf("code: %d, status: %d", code, v)
+		}
+	}
+	unregister()
+	if v := response.HTTPStatus(response.New().SetErrCode(10001)); v != http.StatusOK {
+		t.Errorf("unregistered code status: %d", v)
+	}
+}
+
+func TestBindError(t *testing.T) {
+	var v struct {
+		Page struct {
+			Size int `form:"size"`
+		}
+		Age  int    `form:"age"`
+		Name string `form:"name"`
+	}
+	err := (request.QueryBind{}).Bind(httptest.NewRequest(http.MethodGet, "/?size=x&age=1a&name=gsf", nil), &v)
+	var be *request.BindError
+	if !errors.As(err, &be) || len(be.Fields) != 2 {
+		t.Fatalf("bind error: %v", err)
+	}
+	if f := be.Fields[0]; f.Path != "Page.Size" || f.Field != "size" || f.Source != request.SourceQuery || f.Value != "x" {
+		t.Errorf("field: %+v", f)
+	}
+	if v.Name != "gsf" {
+		t.Errorf("name: %s", v.Name)
+	}
+
+	ret := response.New().AddError(err)
+	data, _ := json.Marshal(ret)
+	var body struct {
+		Errors []string `json:"errors"`
+	}
+	if _ = json.Unmarshal(data, &body); len(body.Errors) != 2 {
+		t.Errorf("errors: %s", data)
+	}
+}
+
+func TestValidator(t *testing.T) {
+	v := request.NewValidator().Register("even", func(value reflect.Value, _ string) bool {
+		return value.Int()%2 == 0
+	})
+	type item struct {
+		Kind string `json:"kind" validate:"oneof=a b"`
+	}
+	type inner struct {
+		Mode string `json:"mode" validate:"oneof=x y"`
+	}
+	var obj struct {
+		inner
+		Name  string  `json:"name" validate:"required,max=4"`
+		Email string  `json:"email" validate:"omitempty,email"`
+		Age   *int    `json:"age" validate:"required,min=1"`
+		Num   int     `json:"num" validate:"even"`
+		Items []*item `json:"items" validate:"min=1"`
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"mode":"z","name":"gsf-fof","email":"x@gsf.dev","num":3,"items":[{"kind":"a"},{"kind":"c"}]}`))
+	err := (request.JsonBind{Validator: v}).Bind(req, &obj)
+
+	var be *request.BindError
+	if !errors.As(err, &be) {
+		t.Fatalf("validate error: %v", err)
+	}
+	paths := make([]string, 0, len(be.Fields))
+	for _, f := range be.Fields {
+		paths = append(paths, f.Path+":"+f.Reason)
+	}
+	if s := strings.Join(paths, ","); s != "Mode:oneof=x y,Name:max=4,Age:required,Num:even,Items[1].Kind:oneof=a b" {
+		t.Errorf("fields: %s", s)
+	}
+
+	// 未注册的规则为配置错误，字段为空时同样 panic
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(error).Error(), "uuid") {
+			t.Errorf("unregistered rule should panic: %v", r)
+		}
+	}()
+	var typo struct {
+		Code string `json:"code" validate:"omitempty,uuid"`
+	}
+	_ = v.Validate(&typo)
+}
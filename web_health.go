@@ -0,0 +1,69 @@
+package gsf
+
+import (
+	"errors"
+	"github.com/kzangv/gsf-fof/logger"
+	"net"
+	"net/http"
+	"sync/atomic"
+)
+
+const (
+	WebHealthPath = "/healthz"
+	WebReadyPath  = "/readyz"
+)
+
+var (
+	ErrWebClosing = errors.New("web service is closing")
+)
+
+// BindReady 绑定就绪检查函数，由 Application 在启动时调用
+func (c *WebService) BindReady(ready func() error) {
+	c.ready = ready
+}
+
+// Ready 就绪检查，服务关闭开始后即返回错误
+func (c *WebService) Ready() error {
+	if atomic.LoadInt32(&c.closing) == 1 {
+		return ErrWebClosing
+	}
+	if c.ready != nil {
+		return c.ready()
+	}
+	return nil
+}
+
+func (c *WebService) healthHandler(next http.Handler) http.Handler {
+	if next == nil {
+		next = http.DefaultServeMux
+	}
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case WebHealthPath:
+			_WriteHealth(resp, nil)
+		case WebReadyPath:
+			_WriteHealth(resp, c.Ready())
+		default:
+			next.ServeHTTP(resp, req)
+		}
+	})
+}
+
+func (c *WebService) runHealth(l logger.Interface, srv *http.Server, ln net.Listener) {
+	l.WarnForce("Listening Health Server http://%s%s\n", ln.Addr().String(), WebReadyPath)
+	if err := srv.Serve(ln); err != http.ErrServerClosed {
+		l.ErrorForce("Health Server Error: %s", err.Error())
+		_ = srv.Close()
+	}
+}
+
+func _WriteHealth(resp http.ResponseWriter, err error) {
+	resp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err != nil {
+		resp.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = resp.Write([]byte(err.Error()))
+		return
+	}
+	resp.WriteHeader(http.StatusOK)
+	_, _ = resp.Write([]byte("ok"))
+}
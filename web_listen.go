@@ -0,0 +1,134 @@
+package gsf
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	_SystemdListenFdsStart = 3
+
+	_WebHealthListener = "health" // 平滑重启时健康检查服务的监听名称
+)
+
+// Addr 服务实际监听的地址，服务开始监听前返回 nil
+func (c *WebService) Addr() net.Addr {
+	if v, ok := c.addr.Load().(net.Addr); ok {
+		return v
+	}
+	return nil
+}
+
+func (c *WebService) listen() (ln net.Listener, err error) {
+	switch {
+	case c.Listener != nil:
+		ln = c.Listener
+	case c.Cfg.Systemd:
+		ln, err = _SystemdListener()
+	case c.Cfg.Unix != "":
+		ln, err = _UnixListener(c.Cfg.Unix)
+	default:
+		ln, err = net.Listen("tcp", fmt.Sprintf("%s:%d", c.Cfg.IP, c.Cfg.Port))
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.ln = ln
+	return ln, nil
+}
+
+// listenHealth 独立的健康检查服务监听，平滑重启时优先使用旧进程传递的监听
+func (c *WebService) listenHealth() (ln net.Listener, err error) {
+	if c.healthInh != nil {
+		ln, c.healthInh = c.healthInh, nil
+	} else if ln, err = net.Listen("tcp", c.Cfg.Health.Addr); err != nil {
+		return nil, err
+	}
+	c.healthLn = ln
+	return ln, nil
+}
+
+// ListenerFiles 平滑重启时返回监听的文件描述符副本，传递给新进程
+func (c *WebService) ListenerFiles() (map[string]*os.File, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.ln == nil || c.closed {
+		return nil, errors.New("Web Service Is Not Listening")
+	}
+	f, err := _ListenerFile(c.ln)
+	if err != nil {
+		return nil, err
+	}
+	ret := map[string]*os.File{"": f}
+	if c.healthLn != nil {
+		if f, err = _ListenerFile(c.healthLn); err != nil {
+			_ = ret[""].Close()
+			return nil, err
+		}
+		ret[_WebHealthListener] = f
+	}
+	return ret, nil
+}
+
+// InheritListeners 使用旧进程传递的监听，优先于 Cfg 中的监听配置
+func (c *WebService) InheritListeners(lns map[string]net.Listener) {
+	for name, ln := range lns {
+		switch name {
+		case "":
+			c.Listener = ln
+		case _WebHealthListener:
+			c.healthInh = ln
+		default:
+			_ = ln.Close()
+		}
+	}
+}
+
+func _ListenerFile(ln net.Listener) (*os.File, error) {
+	// 新进程继续使用 socket 文件，关闭时不删除
+	if ul, ok := ln.(*net.UnixListener); ok {
+		ul.SetUnlinkOnClose(false)
+	}
+	if fl, ok := ln.(interface{ File() (*os.File, error) }); ok {
+		return fl.File()
+	}
+	return nil, errors.New("Web Listener Not Support File")
+}
+
+// _UnixListener 监听 unix socket，清理上次运行遗留的 socket 文件，socket 仍有进程监听时返回错误
+func _UnixListener(path string) (net.Listener, error) {
+	if fi, err := os.Stat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, errors.New("Web Unix Path Is Not Socket: " + path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			_ = conn.Close()
+			return nil, errors.New("Web Unix Path Is In Use: " + path)
+		}
+		if err = os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// _SystemdListener 获取 systemd socket activation 传入的第一个监听
+func _SystemdListener() (net.Listener, error) {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, errors.New("Web Systemd LISTEN_PID Is Invalid")
+	}
+	if n, err := strconv.Atoi(os.Getenv("LISTEN_FDS")); err != nil || n < 1 {
+		return nil, errors.New("Web Systemd LISTEN_FDS Is Invalid")
+	}
+	_ = os.Unsetenv("LISTEN_PID")
+	_ = os.Unsetenv("LISTEN_FDS")
+	_ = os.Unsetenv("LISTEN_FDNAMES")
+
+	f := os.NewFile(uintptr(_SystemdListenFdsStart), "LISTEN_FD_"+strconv.Itoa(_SystemdListenFdsStart))
+	defer f.Close()
+	return net.FileListener(f)
+}
@@ -0,0 +1,487 @@
+package gsf
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"github.com/kzangv/gsf-fof/logger"
+	"github.com/kzangv/gsf-fof/web/middleware"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func _WriteTestCert(t *testing.T, dir string) (certFile, keyFile string, pool *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err = os.WriteFile(certFile, certPem, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	pool = x509.NewCertPool()
+	pool.AppendCertsFromPEM(certPem)
+	return
+}
+
+func TestWebTLS(t *testing.T) {
+	certFile, keyFile, pool := _WriteTestCert(t, t.TempDir())
+	ser := &WebService{Handler: http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		_, _ = resp.Write([]byte(req.Proto))
+	})}
+	_RunTestWeb(t, ser, []string{"--web-ip=127.0.0.1", "--web-port=0", "--web-tls-cert=" + certFile, "--web-tls-key=" + keyFile}, func(_ *http.Client, addr net.Addr) {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: pool},
+			ForceAttemptHTTP2: true,
+		}}
+		resp, err := client.Get("https://" + addr.String() + "/")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		_ = resp.Body.Close()
+		if resp.ProtoMajor != 2 {
+			t.Errorf("http2 should be enabled: %s", resp.Proto)
+		}
+	})
+}
+
+func _RunTestWeb(t *testing.T, ser *WebService, args []string, check func(client *http.Client, addr net.Addr)) {
+	_RunTestApp(t, &Application{Ser: ser}, ser, args, check)
+}
+
+// _RunTestApp 运行 app，ser 开始监听后执行 check，check 返回后 app 退出
+func _RunTestApp(t *testing.T, app *Application, ser *WebService, args []string, check func(client *http.Client, addr net.Addr)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for ser.Addr() == nil {
+			time.Sleep(time.Millisecond * 10)
+		}
+		client := &http.Client{}
+		if ser.Addr().Network() == "unix" {
+			client.Transport = &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", ser.Addr().String())
+			}}
+		}
+		check(client, ser.Addr())
+	}()
+
+	if err := app.Run(ctx, append([]string{"test"}, args...)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebMTLS(t *testing.T) {
+	certFile, keyFile, pool := _WriteTestCert(t, t.TempDir())
+	clientCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct {
+		auth       string
+		noCertFail bool
+	}{
+		{WebTLSClientAuthRequire, true},
+		{WebTLSClientAuthVerify, false},
+	} {
+		args := []string{"--web-ip=127.0.0.1", "--web-port=0", "--web-tls-cert=" + certFile, "--web-tls-key=" + keyFile,
+			"--web-tls-client-ca=" + certFile, "--web-tls-client-auth=" + v.auth}
+		_RunTestWeb(t, &WebService{Handler: http.NotFoundHandler()}, args, func(_ *http.Client, addr net.Addr) {
+			for _, certs := range [][]tls.Certificate{nil, {clientCert}} {
+				client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, Certificates: certs}}}
+				resp, err := client.Get("https://" + addr.String() + "/")
+				if err == nil {
+					_ = resp.Body.Close()
+				}
+				if fail := certs == nil && v.noCertFail; (err != nil) != fail {
+					t.Errorf("%s: client cert %d, error: %v", v.auth, len(certs), err)
+				}
+			}
+		})
+	}
+}
+
+func TestWebTLSReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, _ := _WriteTestCert(t, dir)
+
+	// 返回服务当前使用的证书
+	serverCert := func(addr net.Addr) []byte {
+		conn, err := tls.Dial("tcp", addr.String(), &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Error(err)
+			return nil
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].Raw
+	}
+	// 重新生成证书文件，返回新证书
+	rewrite := func() []byte {
+		_, _, _ = _WriteTestCert(t, dir)
+		modTime := time.Now().Add(time.Second)
+		for _, f := range []string{certFile, keyFile} {
+			if err := os.Chtimes(f, modTime, modTime); err != nil {
+				t.Error(err)
+			}
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			t.Error(err)
+			return nil
+		}
+		return cert.Certificate[0]
+	}
+
+	for _, v := range []struct {
+		name   string
+		reload string
+		signal bool
+	}{
+		{"watch", "1", false},
+		{"sighup", "0", true},
+	} {
+		args := []string{"--web-ip=127.0.0.1", "--web-port=0", "--web-tls-cert=" + certFile, "--web-tls-key=" + keyFile, "--web-tls-reload=" + v.reload}
+		_RunTestWeb(t, &WebService{Handler: http.NotFoundHandler()}, args, func(_ *http.Client, addr net.Addr) {
+			cert := rewrite()
+			if v.signal {
+				p, _ := os.FindProcess(os.Getpid())
+				_ = p.Signal(syscall.SIGHUP)
+			}
+			for i := 0; i < 30 && !bytes.Equal(serverCert(addr), cert); i++ {
+				time.Sleep(time.Millisecond * 100)
+			}
+			if !bytes.Equal(serverCert(addr), cert) {
+				t.Errorf("%s: cert should be reloaded", v.name)
+			}
+		})
+	}
+}
+
+func TestWebListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ser := &WebService{Handler: http.NotFoundHandler(), Listener: ln}
+	_RunTestWeb(t, ser, nil, func(client *http.Client, addr net.Addr) {
+		if addr.String() != ln.Addr().String() {
+			t.Errorf("addr: %s", addr.String())
+		}
+		resp, err := client.Get("http://" + addr.String() + "/")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		_ = resp.Body.Close()
+	})
+}
+
+func TestWebUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "web.sock")
+	live, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// socket 仍有进程监听时不能删除
+	if _, err = _UnixListener(path); err == nil {
+		t.Error("socket in use should not be removed")
+	}
+	// 遗留的 socket 文件启动时删除
+	live.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = live.Close()
+
+	ser := &WebService{Handler: http.NotFoundHandler()}
+	_RunTestWeb(t, ser, []string{"--web-unix=" + path}, func(client *http.Client, addr net.Addr) {
+		resp, err := client.Get("http://unix/")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("status: %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestWebListenerHandover(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("listener handover is not supported on windows")
+	}
+
+	var (
+		ln   net.Listener
+		addr string
+	)
+	old := &WebService{Handler: http.NotFoundHandler()}
+	_RunTestWeb(t, old, []string{"--web-ip=127.0.0.1", "--web-port=0"}, func(_ *http.Client, a net.Addr) {
+		fs, err := old.ListenerFiles()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		f := fs[""]
+		defer f.Close()
+		if ln, err = net.FileListener(f); err != nil {
+			t.Error(err)
+		}
+		addr = a.String()
+	})
+	if ln == nil {
+		t.FailNow()
+	}
+
+	// 旧服务退出后，新服务继续在同一地址提供服务
+	ser := &WebService{Handler: http.NotFoundHandler()}
+	ser.InheritListeners(map[string]net.Listener{"": ln})
+	_RunTestWeb(t, ser, nil, func(client *http.Client, _ net.Addr) {
+		resp, err := client.Get("http://" + addr + "/")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		_ = resp.Body.Close()
+	})
+}
+
+func TestWebMiddleware(t *testing.T) {
+	const codePanic = 1500
+	l := logger.ToNull(logger.Error)
+	ser := &WebService{Handler: http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if middleware.GetRequestID(req.Context()) == "" {
+			t.Error("request id should be set")
+		}
+		panic("test panic")
+	})}
+	ser.Use(middleware.RequestID(), middleware.AccessLog(&l), middleware.Recovery(&l, codePanic))
+	_RunTestWeb(t, ser, []string{"--web-ip=127.0.0.1", "--web-port=0"}, func(client *http.Client, addr net.Addr) {
+		req, _ := http.NewRequest(http.MethodGet, "http://"+addr.String()+"/", nil)
+		req.Header.Set(middleware.HeaderRequestID, "test-rid")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		var body struct {
+			Code int `json:"code"`
+		}
+		if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		if resp.StatusCode != http.StatusInternalServerError || body.Code != codePanic {
+			t.Errorf("status: %d, code: %d", resp.StatusCode, body.Code)
+		}
+		if rid := resp.Header.Get(middleware.HeaderRequestID); rid != "test-rid" {
+			t.Errorf("request id: %s", rid)
+		}
+	})
+}
+
+func TestWebHijack(t *testing.T) {
+	l := logger.ToNull(logger.Error)
+	srv := httptest.NewServer(middleware.Chain(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		h, ok := resp.(http.Hijacker)
+		if !ok {
+			t.Error("response writer should support hijack")
+			return
+		}
+		conn, buf, err := h.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		_, _ = buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		_ = buf.Flush()
+	}), middleware.AccessLog(&l)))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if data, _ := io.ReadAll(resp.Body); string(data) != "hijacked" {
+		t.Errorf("body: %s", data)
+	}
+}
+
+func TestWebDrain(t *testing.T) {
+	started := make(chan struct{})
+	ser := &WebService{Handler: http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		close(started)
+		time.Sleep(time.Millisecond * 500)
+		_, _ = resp.Write([]byte("done"))
+	})}
+	app := Application{Ser: ser}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	result, checked := make(chan error, 1), make(chan struct{})
+	go func() {
+		defer close(checked)
+		for ser.Addr() == nil {
+			time.Sleep(time.Millisecond * 10)
+		}
+		addr := ser.Addr().String()
+		go func() {
+			resp, err := http.Get("http://" + addr + "/")
+			if err == nil {
+				data, _ := io.ReadAll(resp.Body)
+				_ = resp.Body.Close()
+				if string(data) != "done" {
+					err = errors.New("in-flight request body: " + string(data))
+				}
+			}
+			result <- err
+		}()
+
+		// 请求处理中关闭，新连接被拒绝，正在处理的请求正常完成
+		<-started
+		cancel()
+		time.Sleep(time.Millisecond * 100)
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			_ = conn.Close()
+			t.Error("new connection should be refused while draining")
+		}
+	}()
+
+	if err := app.Run(ctx, []string{"test", "--web-ip=127.0.0.1", "--web-port=0"}); err != nil {
+		t.Fatal(err)
+	}
+	<-checked
+	if err := <-result; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWebShutdownDelay(t *testing.T) {
+	ser := &WebService{Handler: http.HandlerFunc(func(resp http.ResponseWriter, _ *http.Request) {
+		_, _ = resp.Write([]byte("ok"))
+	})}
+	app := Application{Ser: ser}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	checked := make(chan struct{})
+	go func() {
+		defer close(checked)
+		for ser.Addr() == nil {
+			time.Sleep(time.Millisecond * 10)
+		}
+		addr := "http://" + ser.Addr().String()
+
+		// 延迟期间就绪检查失败，请求正常处理
+		cancel()
+		time.Sleep(time.Millisecond * 200)
+		for path, code := range map[string]int{WebReadyPath: http.StatusServiceUnavailable, "/": http.StatusOK} {
+			resp, err := http.Get(addr + path)
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != code {
+				t.Errorf("%s: %d", path, resp.StatusCode)
+			}
+		}
+	}()
+
+	begin := time.Now()
+	if err := app.Run(ctx, []string{"test", "--web-ip=127.0.0.1", "--web-port=0", "--web-health", "--web-shutdown-delay=1"}); err != nil {
+		t.Fatal(err)
+	}
+	<-checked
+	if time.Since(begin) < time.Second {
+		t.Errorf("shutdown should be delayed")
+	}
+}
+
+func TestWebHealthListenFail(t *testing.T) {
+	used, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer used.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// 健康检查监听失败时服务启动失败，已监听的端口被释放
+	ser := &WebService{Handler: http.NotFoundHandler(), Listener: ln}
+	err = (&Application{Ser: ser}).Run(context.Background(), []string{"test", "--web-health", "--web-health-addr=" + used.Addr().String()})
+	if err == nil {
+		t.Fatal("health listen should fail")
+	}
+	if _, err = ln.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("web listener should be closed: %v", err)
+	}
+}
+
+func TestWebTimeout(t *testing.T) {
+	const codeTimeout = 1504
+	h := middleware.Chain(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	}), middleware.Timeout(time.Millisecond*10, codeTimeout))
+
+	// 超时响应按 Accept 输出
+	for accept, ct := range map[string]string{"": "application/json", "application/xml": "application/xml"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusServiceUnavailable || !strings.HasPrefix(rec.Header().Get("Content-Type"), ct) ||
+			!strings.Contains(rec.Body.String(), "1504") {
+			t.Errorf("accept %q: %d %s %s", accept, rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package gsf
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"github.com/kzangv/gsf-fof/logger"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	WebTLSClientAuthRequire = "require" // 必须提供并校验客户端证书
+	WebTLSClientAuthVerify  = "verify"  // 客户端提供证书时校验
+)
+
+// _CertLoader 证书加载器，支持运行时重新加载证书
+type _CertLoader struct {
+	certFile, keyFile string
+
+	lock    sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func (l *_CertLoader) fileModTime() time.Time {
+	var ret time.Time
+	for _, f := range []string{l.certFile, l.keyFile} {
+		if fi, err := os.Stat(f); err == nil && fi.ModTime().After(ret) {
+			ret = fi.ModTime()
+		}
+	}
+	return ret
+}
+
+func (l *_CertLoader) Load() error {
+	modTime := l.fileModTime()
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		return err
+	}
+	l.lock.Lock()
+	l.cert, l.modTime = &cert, modTime
+	l.lock.Unlock()
+	return nil
+}
+
+func (l *_CertLoader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	return l.cert, nil
+}
+
+// Watch 定时检查证书文件修改时间，变化时重新加载
+func (l *_CertLoader) Watch(log logger.Interface, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.lock.RLock()
+			modTime := l.modTime
+			l.lock.RUnlock()
+			if l.fileModTime().After(modTime) {
+				if err := l.Load(); err != nil {
+					log.ErrorForce("Web TLS Cert Reload Error: %s", err.Error())
+				} else {
+					log.WarnForce("Web TLS Cert Reload\n")
+				}
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func _TLSVersion(v string) (uint16, error) {
+	switch v {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, errors.New("Web TLS Version Is Invalid: " + v)
+}
+
+// tlsConfig 根据配置创建 TLS 配置，未配置证书时返回 nil
+func (c *WebService) tlsConfig() (*tls.Config, error) {
+	cfg := &c.Cfg.TLS
+	if cfg.Cert == "" && cfg.Key == "" {
+		return nil, nil
+	}
+
+	c.cert = &_CertLoader{certFile: cfg.Cert, keyFile: cfg.Key}
+	if err := c.cert.Load(); err != nil {
+		return nil, err
+	}
+	ver, err := _TLSVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	ret := &tls.Config{
+		MinVersion:     ver,
+		GetCertificate: c.cert.GetCertificate,
+	}
+
+	// mTLS
+	if cfg.ClientCA != "" {
+		data, err := os.ReadFile(cfg.ClientCA)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, errors.New("Web TLS Client CA Is Invalid: " + cfg.ClientCA)
+		}
+		ret.ClientCAs = pool
+		switch cfg.ClientAuth {
+		case "", WebTLSClientAuthRequire:
+			ret.ClientAuth = tls.RequireAndVerifyClientCert
+		case WebTLSClientAuthVerify:
+			ret.ClientAuth = tls.VerifyClientCertIfGiven
+		default:
+			return nil, errors.New("Web TLS Client Auth Is Invalid: " + cfg.ClientAuth)
+		}
+	}
+	return ret, nil
+}
+
+// Reload 收到 SIGHUP 时重新加载证书
+func (c *WebService) Reload(l logger.Interface, _ Config) error {
+	c.lock.Lock()
+	cert := c.cert
+	c.lock.Unlock()
+	if cert == nil {
+		return nil
+	}
+	if err := cert.Load(); err != nil {
+		return err
+	}
+	l.WarnForce("Web TLS Cert Reload\n")
+	return nil
+}
+
+func (c *WebService) setupTLS(l logger.Interface, srv *http.Server) (bool, error) {
+	tlsCfg, err := c.tlsConfig()
+	if err != nil || tlsCfg == nil {
+		return false, err
+	}
+	srv.TLSConfig = tlsCfg
+	if c.Cfg.TLS.DisableHTTP2 {
+		srv.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+	if c.Cfg.TLS.Reload > 0 {
+		c.stop = make(chan struct{})
+		go c.cert.Watch(l, time.Duration(c.Cfg.TLS.Reload)*time.Second, c.stop)
+	}
+	return true, nil
+}